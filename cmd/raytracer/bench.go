@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/thijsheijden/go-raytracer/film"
+	"github.com/thijsheijden/go-raytracer/render"
+	"github.com/thijsheijden/go-raytracer/scene"
+	"time"
+)
+
+// runBench renders a scene a number of times and reports the ray throughput, to track performance across commits
+func runBench(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	scenePath := flags.String("scene", "", "JSON scene description to render, the default is a scene full of spheres")
+	width := flags.Int("width", 320, "width of the rendered image in pixels")
+	samples := flags.Int("samples", 8, "samples per pixel")
+	depth := flags.Int("depth", maxDepth, "maximum number of bounces of a path")
+	threads := flags.Int("threads", nThreads, "number of threads to split the work up")
+	spectral := flags.Bool("spectral", false, "trace a single wavelength per path")
+	runs := flags.Int("runs", 3, "number of renders, the fastest one is reported")
+	flags.Parse(args)
+	if flags.NArg() > 0 {
+		return &usageError{fmt.Sprintf("unexpected argument %q", flags.Arg(0))}
+	}
+	if *width <= 0 || *samples <= 0 || *depth <= 0 || *threads <= 0 || *runs <= 0 {
+		return &usageError{"-width, -samples, -depth, -threads and -runs must be positive"}
+	}
+
+	description := defaultScene(1)
+	if *scenePath != "" {
+		var err error
+		description, err = scene.Load(*scenePath)
+		if err != nil {
+			return err
+		}
+	}
+	description.ImageWidth = *width
+	s, err := description.Build()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%dx%d pixels, %d spheres, %d samples per pixel, %d threads\n", s.ImageWidth, s.ImageHeight, len(s.Spheres), *samples, *threads)
+	options := render.Options{
+		SamplesPerPixel: *samples,
+		MaxDepth:        *depth,
+		Threads:         *threads,
+		Filter:          film.Box(0.5),
+		Seed:            1,
+		Spectral:        *spectral,
+	}
+
+	// Every run traces the same rays, they are counted in a render of its own so counting does not slow the timed runs
+	var stats render.Stats
+	counted := options
+	counted.Stats = &stats
+	render.New(&s, counted).Render(context.Background())
+
+	var best float64
+	for run := 1; run <= *runs; run++ {
+		renderer := render.New(&s, options)
+		start := time.Now()
+		renderer.Render(context.Background())
+		elapsed := time.Since(start)
+
+		mrays := float64(stats.Rays()) / elapsed.Seconds() / 1e6
+		if mrays > best {
+			best = mrays
+		}
+		fmt.Printf("run %d: %d rays in %v, %.3f Mrays/s\n", run, stats.Rays(), elapsed.Round(time.Millisecond), mrays)
+	}
+	fmt.Printf("best: %.3f Mrays/s\n", best)
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/thijsheijden/go-raytracer"
+	"github.com/thijsheijden/go-raytracer/compare"
+	"image"
+	"os"
+)
+
+// runDiff compares two images and writes a heatmap of their differences
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: raytracer diff [flags] reference.png test.png")
+		flags.PrintDefaults()
+	}
+	heatmapPath := flags.String("heatmap", "diff.png", "write the perceptual error per pixel in false color to this file, empty to skip")
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		return &usageError{fmt.Sprintf("diff needs a reference image and an image to compare with it, got %d arguments", flags.NArg())}
+	}
+
+	reference, err := readImage(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	test, err := readImage(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	result, err := compare.Images(reference, test)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("MSE   %.6f\n", result.MSE)
+	fmt.Printf("RMSE  %.6f\n", result.RMSE)
+	fmt.Printf("PSNR  %.2f dB\n", result.PSNR)
+	fmt.Printf("SSIM  %.4f\n", result.SSIM)
+	fmt.Printf("FLIP  %.4f\n", result.FLIP)
+
+	if *heatmapPath == "" {
+		return nil
+	}
+	return raytracer.WriteImage(*heatmapPath, result.Heatmap())
+}
+
+// readImage decodes a PNG or JPEG image
+func readImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading image %s: %w", path, err)
+	}
+	return img, nil
+}
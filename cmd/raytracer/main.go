@@ -1,148 +1,359 @@
 package main
 
 import (
-	"image"
-	"image/draw"
-	"image/jpeg"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/thijsheijden/go-raytracer"
+	"github.com/thijsheijden/go-raytracer/film"
+	"github.com/thijsheijden/go-raytracer/internal/terminal"
+	"github.com/thijsheijden/go-raytracer/render"
+	"github.com/thijsheijden/go-raytracer/scene"
+	"github.com/thijsheijden/go-raytracer/tonemap"
+	"github.com/thijsheijden/go-raytracer/vector"
 	"log"
-	"math"
-	"math/rand"
+	"net/http"
 	"os"
-	"raytracer/internal/color"
-	"raytracer/internal/object"
-	"raytracer/internal/ray"
-	"raytracer/internal/scene"
-	"raytracer/internal/vector"
+	"os/signal"
+	"runtime"
 	"runtime/pprof"
-	"sync"
+	"strings"
 	"time"
-
-	_ "image/jpeg" // Needed for JPEG decoder
 )
 
-var loadedScene scene.Scene
 var nPixelSamples = 500
 var maxDepth = 50
-var infinity = math.Inf(1)
 var imageWidth = 1080
 
 // Number of threads to split the work up
 const nThreads = 16
 
-type imagePart struct {
-	index, height int
-	image         *image.RGBA
-}
+// The file the rendered image is written to
+const outputPath = "outimage.jpg"
 
 func main() {
-	const aspectRatio = 16.0 / 9.0
-	loadedScene = scene.New(scene.NewCamera(vector.New(13, 2, 3), vector.New(0, 0, 0), vector.New(0, 1, 0), 20, aspectRatio, 1.0), aspectRatio, imageWidth)
-	loadedScene.LotsOfSpheres()
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "raytracer: %v\n", err)
 
-	var rowsPerThread = loadedScene.ImageHeight / nThreads
-	var rest = loadedScene.ImageHeight - (nThreads * rowsPerThread)
+		// Like the flag package, exit with 2 for invalid arguments
+		var usage *usageError
+		if errors.As(err, &usage) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
 
-	cpuProfile, err := os.Create("profile.pprof")
-	if err != nil {
-		log.Fatal(err)
-	}
-	pprof.StartCPUProfile(cpuProfile)
-	defer pprof.StopCPUProfile()
-
-	var wg sync.WaitGroup
-	imagePartChan := make(chan imagePart, nThreads)
-	for thread := 0; thread < nThreads; thread++ {
-		// If this is the last thread, it gets the remaining rows from incomplete division
-		startY := thread * rowsPerThread
-		endY := (thread + 1) * rowsPerThread
-		if thread == nThreads-1 {
-			endY += rest
+// run runs the subcommand in args, rendering an image when there is none
+func run(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "worker":
+			return runWorker(args[1:])
+		case "serve":
+			return runServe(args[1:])
+		case "diff":
+			return runDiff(args[1:])
+		case "bench":
+			return runBench(args[1:])
 		}
+	}
+	return runRender(args)
+}
 
-		// Generate random generator
-		random := rand.New(rand.NewSource(time.Now().UnixNano()))
-
-		// Join waitgroup
-		wg.Add(1)
-
-		go func(thread, startY, endY int, random *rand.Rand, imagePartChan chan imagePart) {
-			defer wg.Done()
-			// Create slice for image part
-			img := image.NewRGBA(image.Rect(0, 0, loadedScene.ImageWidth, (endY - startY)))
-
-			// Cast rays for each of the image pixels
-			for y := startY; y <= endY; y++ {
-				for x := 0; x < loadedScene.ImageWidth; x++ {
-					// Define a new color for this pixel, which we will average later
-					var pixelColor color.RGB = color.New(0, 0, 0)
-
-					// Anti-aliasing
-					for i := 0; i < nPixelSamples; i++ {
-						var u float64 = (float64(x) + random.Float64()) / (loadedScene.FloatImageWidth + 1.0)
-						var v float64 = (float64(y) + random.Float64()) / float64(loadedScene.FloatImageHeight+1)
-						r := ray.New(loadedScene.Origin, loadedScene.LowerLeftCorner.Add(loadedScene.Horizontal.Scale(u)).Add(loadedScene.Vertical.Scale(v)).Sub(loadedScene.Origin))
-						pixelColor = pixelColor.Add(colorRay(r, maxDepth, random))
-					}
-
-					// Set pixel in slice
-					img.Set(x, endY-y, pixelColor.Average(nPixelSamples).RGBA())
-				}
-			}
+// usageError reports invalid command line arguments
+type usageError struct {
+	message string
+}
 
-			// Thread is done
-			imagePartChan <- imagePart{index: nThreads - thread - 1, height: endY - startY, image: img}
+func (e *usageError) Error() string {
+	return e.message
+}
 
-		}(thread, startY, endY, random, imagePartChan)
-	}
+// runRender renders an image
+func runRender(args []string) (err error) {
+	flags := flag.NewFlagSet("raytracer", flag.ExitOnError)
+	scenePath := flags.String("scene", "", "JSON scene description to render, the default is a scene full of spheres")
+	filterName := flags.String("filter", "box", "pixel reconstruction filter: box, tent, gaussian, mitchell or lanczos")
+	filterRadius := flags.Float64("filter-radius", 0, "reconstruction filter radius in pixels, 0 uses the default of the filter")
+	exposure := flags.Float64("exposure", 0, "exposure compensation in stops (EV)")
+	toneMapping := flags.String("tonemap", "clamp", "tone mapping operator: clamp, reinhard, reinhard-extended, aces or agx")
+	whitePoint := flags.Float64("white-point", 4, "luminance mapped to white by the reinhard-extended operator")
+	progressive := flags.Bool("progressive", false, "refine the whole image pass by pass, one sample per pixel per pass")
+	writeInterval := flags.Duration("write-interval", 10*time.Second, "how often the current image is written during a progressive render")
+	timeLimit := flags.Duration("time-limit", 0, "stop the render after this duration and write what was rendered, 0 means no limit")
+	seed := flags.Int64("seed", 0, "seed for sampling, 0 picks a random seed")
+	sceneSeed := flags.Int64("scene-seed", 1, "seed used to generate the default scene")
+	checkpointPath := flags.String("checkpoint", "", "periodically save the state of a progressive render to this file")
+	checkpointInterval := flags.Duration("checkpoint-interval", time.Minute, "how often the checkpoint is written")
+	resume := flags.Bool("resume", false, "continue the progressive render saved in the checkpoint file")
+	workers := flags.String("workers", "", "comma separated worker URLs to distribute the render over, see raytracer worker -h")
+	tileHeight := flags.Int("tile-height", 16, "number of rows in a tile sent to a worker")
+	tileTimeout := flags.Duration("tile-timeout", 10*time.Minute, "time a worker gets to render a tile before it is handed to another worker")
+	watchScene := flags.Bool("watch", false, "render the -scene file again whenever it changes, at -watch-samples samples per pixel")
+	watchSamples := flags.Int("watch-samples", 8, "samples per pixel of the renders in watch mode")
+	watchInterval := flags.Duration("watch-interval", 500*time.Millisecond, "how often the scene file is checked for changes in watch mode")
+	previewMode := flags.String("preview", "", "draw a live preview in the terminal: auto, truecolor, 256 or sixel")
+	previewWidth := flags.Int("preview-width", 80, "width of the terminal preview in characters")
+	spectral := flags.Bool("spectral", false, "trace a single wavelength per path, for dispersion in glass")
+	printStats := flags.Bool("stats", false, "count rays, intersection tests and path lengths, and print a summary after the render")
+	cpuProfilePath := flags.String("cpuprofile", "", "write a CPU profile of the render to this file")
+	memProfilePath := flags.String("memprofile", "", "write a memory profile to this file after the render")
+	flags.Parse(args)
 
-	// Wait for all threads to finish
-	wg.Wait()
-	close(imagePartChan)
+	if flags.NArg() > 0 {
+		return &usageError{fmt.Sprintf("unexpected argument %q", flags.Arg(0))}
+	}
+	if *resume && *checkpointPath == "" {
+		return &usageError{"-resume needs a -checkpoint file"}
+	}
+	if *checkpointPath != "" && !*progressive {
+		return &usageError{"checkpoints are only supported for -progressive renders"}
+	}
+	if *watchScene && *scenePath == "" {
+		return &usageError{"-watch needs a -scene file"}
+	}
+	if *watchScene && (*progressive || *workers != "" || *timeLimit > 0 || *previewMode != "" || *cpuProfilePath != "" || *memProfilePath != "") {
+		return &usageError{"-watch can not be combined with -progressive, -workers, -time-limit, -preview, -cpuprofile or -memprofile"}
+	}
+	if *workers != "" && *progressive {
+		return &usageError{"-workers can not be combined with -progressive"}
+	}
+	if *workers != "" && *printStats {
+		return &usageError{"-stats is not collected from workers"}
+	}
+	if *tileHeight <= 0 {
+		return &usageError{"-tile-height must be positive"}
+	}
+	if *previewWidth <= 0 {
+		return &usageError{"-preview-width must be positive"}
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
 
-	// Stitch all image parts together
-	fullImage := image.NewRGBA(image.Rect(0, 0, loadedScene.ImageWidth, loadedScene.ImageHeight))
-	for part := range imagePartChan {
-		r := image.Rect(0, part.index*rowsPerThread, loadedScene.ImageWidth, part.index*rowsPerThread+part.height)
-		draw.Draw(fullImage, r, part.image, image.ZP, draw.Src)
+	filter, err := film.FilterByName(*filterName, *filterRadius)
+	if err != nil {
+		return err
 	}
 
-	// Save image
-	output, err := os.Create("outimage.jpg")
+	operator, err := tonemap.OperatorByName(*toneMapping, *whitePoint)
 	if err != nil {
-		// Handle error
+		return err
+	}
+	development := tonemap.Settings{Exposure: *exposure, Operator: operator}
+
+	options := render.Options{
+		SamplesPerPixel: nPixelSamples,
+		MaxDepth:        maxDepth,
+		Threads:         nThreads,
+		Filter:          filter,
+		Seed:            *seed,
+		Spectral:        *spectral,
+	}
+	if *printStats {
+		options.Stats = &render.Stats{}
+	}
+
+	if *watchScene {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		options.SamplesPerPixel = *watchSamples
+		return watch(ctx, *scenePath, options, development, *watchInterval)
 	}
-	defer output.Close()
 
-	// Specify the quality, between 0-100
-	// Higher is better
-	opt := jpeg.Options{
-		Quality: 90,
+	description := defaultScene(*sceneSeed)
+	if *scenePath != "" {
+		description, err = scene.Load(*scenePath)
+		if err != nil {
+			return err
+		}
 	}
-	err = jpeg.Encode(output, fullImage, &opt)
+	loadedScene, err := description.Build()
 	if err != nil {
-		// Handle error
+		return err
 	}
-}
 
-// Red to blue gradient based on y coord
-func colorRay(r ray.Ray, depth int, random *rand.Rand) color.RGB {
-	// Reached max recursion depth
-	if depth <= 0 {
-		return color.New(0, 0, 0)
+	if *cpuProfilePath != "" {
+		stopProfile, err := startCPUProfile(*cpuProfilePath)
+		if err != nil {
+			return err
+		}
+		defer stopProfile()
+	}
+	if *memProfilePath != "" {
+		defer func() {
+			if profileErr := writeMemProfile(*memProfilePath); profileErr != nil && err == nil {
+				err = profileErr
+			}
+		}()
+	}
+
+	var preview *terminalPreview
+	if *previewMode != "" {
+		mode, err := terminal.ModeByName(*previewMode)
+		if err != nil {
+			return err
+		}
+		preview = newTerminalPreview(mode, *previewWidth, development)
+		options.OnTile = preview.update
+	}
+	renderer := render.New(&loadedScene, options)
+
+	// Stop on interrupt or when the time limit is reached, the samples taken so far are still written
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *timeLimit > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeLimit)
+		defer cancel()
 	}
 
-	var hit object.Hit
+	start := time.Now()
+	var fullFilm *film.Film
+	if *progressive {
+		lastWrite, lastCheckpoint := time.Now(), time.Now()
+		onPass := func(pass int, f *film.Film) {
+			if preview != nil {
+				preview.update(f)
+			}
+			// Failing intermediate writes are reported, the render goes on
+			if *checkpointPath != "" && time.Since(lastCheckpoint) >= *checkpointInterval {
+				lastCheckpoint = time.Now()
+				if err := writeCheckpoint(renderer, *checkpointPath); err != nil {
+					log.Print(err)
+				}
+			}
+			if time.Since(lastWrite) < *writeInterval {
+				return
+			}
+			lastWrite = time.Now()
+			if err := raytracer.WriteImage(outputPath, raytracer.Develop(f, development)); err != nil {
+				log.Print(err)
+				return
+			}
+			log.Printf("pass %d/%d, written to %s", pass, nPixelSamples, outputPath)
+		}
 
-	if loadedScene.Hit(&r, 0.001, infinity, &hit) {
-		var scattered ray.Ray
-		var attenuation color.RGB
+		var passes int
+		if *resume {
+			checkpoint, err := render.ReadCheckpoint(*checkpointPath)
+			if err != nil {
+				return err
+			}
+			log.Printf("resuming after pass %d from %s", checkpoint.Passes, *checkpointPath)
+			fullFilm, passes, err = renderer.Resume(ctx, checkpoint, onPass)
+			if err != nil {
+				return fmt.Errorf("cannot resume: %w", err)
+			}
+		} else {
+			fullFilm, passes = renderer.Progressive(ctx, onPass)
+		}
+		log.Printf("completed %d of %d passes", passes, nPixelSamples)
 
-		if hit.Material.Scatter(&r, &hit, &attenuation, &scattered, random) {
-			return colorRay(scattered, depth-1, random).Mul(attenuation.R, attenuation.G, attenuation.B)
+		if *checkpointPath != "" {
+			// The image is still written when the checkpoint fails
+			defer func() {
+				if checkpointErr := writeCheckpoint(renderer, *checkpointPath); checkpointErr != nil && err == nil {
+					err = checkpointErr
+				}
+			}()
+		}
+	} else if *workers != "" {
+		coordinator := render.Coordinator{
+			Scene:       description,
+			Options:     options,
+			Workers:     strings.Split(*workers, ","),
+			TileHeight:  *tileHeight,
+			TileTimeout: *tileTimeout,
+			MaxFailures: 3,
+			Client:      http.DefaultClient,
+		}
+		fullFilm, err = coordinator.Render(ctx)
+		if err != nil {
+			return err
+		}
+	} else {
+		fullFilm, err = raytracer.Render(ctx, &loadedScene, options)
+		if err != nil && ctx.Err() == nil {
+			return err
 		}
-		return color.New(0, 0, 0)
 	}
 
-	t := float32(0.5 * (r.Direction().Normalise().Y + 1.0))
-	return color.New(1, 1, 1).Mul(1-t, 1-t, 1-t).Add(color.New(0.5, 0.7, 1).Mul(t, t, t))
+	if preview != nil {
+		preview.draw(fullFilm)
+	}
+	if ctx.Err() != nil {
+		log.Printf("render stopped early: %v", ctx.Err())
+	}
+	nPixels := float64(fullFilm.Width * fullFilm.Height)
+	log.Printf("took %d samples in %v, %.1f of %d samples per pixel", fullFilm.TotalSamples(), time.Since(start).Round(time.Millisecond),
+		float64(fullFilm.TotalSamples())/nPixels, nPixelSamples)
+	if options.Stats != nil {
+		fmt.Fprint(os.Stderr, options.Stats.Summary())
+	}
+
+	// Develop the film, applying exposure, tone mapping and the sRGB transfer function
+	return raytracer.WriteImage(outputPath, raytracer.Develop(fullFilm, development))
+}
+
+// defaultScene describes the scene full of randomly placed spheres
+func defaultScene(seed int64) scene.Description {
+	return scene.Description{
+		Camera: scene.CameraDescription{
+			Position:    vector.New(13, 2, 3),
+			LookAt:      vector.New(0, 0, 0),
+			VUp:         vector.New(0, 1, 0),
+			VerticalFOV: 20,
+			FocalLength: 1.0,
+		},
+		AspectRatio: 16.0 / 9.0,
+		ImageWidth:  imageWidth,
+		Preset:      "lotsOfSpheres",
+		Seed:        seed,
+	}
+}
+
+// writeCheckpoint saves the state of the progressive render
+func writeCheckpoint(renderer *render.Renderer, path string) error {
+	checkpoint, err := renderer.Checkpoint()
+	if err != nil {
+		return err
+	}
+	if err := render.WriteCheckpoint(path, checkpoint); err != nil {
+		return err
+	}
+	log.Printf("checkpoint after pass %d written to %s", checkpoint.Passes, path)
+	return nil
+}
+
+// startCPUProfile starts writing a CPU profile to path, the returned function stops it
+func startCPUProfile(path string) (func(), error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.StartCPUProfile(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return func() {
+		pprof.StopCPUProfile()
+		file.Close()
+	}, nil
+}
+
+// writeMemProfile writes a heap profile to path
+func writeMemProfile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"github.com/thijsheijden/go-raytracer/film"
+	"github.com/thijsheijden/go-raytracer/internal/terminal"
+	"github.com/thijsheijden/go-raytracer/tonemap"
+	"log"
+	"os"
+	"time"
+)
+
+// Minimum time between two redraws of the terminal preview
+const previewInterval = 250 * time.Millisecond
+
+// terminalPreview draws a downscaled version of the film in the terminal
+type terminalPreview struct {
+	preview     *terminal.Preview
+	mode        terminal.Mode
+	width       int // Width in characters
+	development tonemap.Settings
+	lastDraw    time.Time
+}
+
+func newTerminalPreview(mode terminal.Mode, width int, development tonemap.Settings) *terminalPreview {
+	return &terminalPreview{
+		preview:     terminal.New(os.Stdout, mode),
+		mode:        mode,
+		width:       width,
+		development: development,
+	}
+}
+
+// update redraws the preview, unless it was drawn very recently
+func (p *terminalPreview) update(f *film.Film) {
+	if time.Since(p.lastDraw) < previewInterval {
+		return
+	}
+	p.draw(f)
+}
+
+// draw redraws the preview
+func (p *terminalPreview) draw(f *film.Film) {
+	p.lastDraw = time.Now()
+
+	// Characters show two pixels on top of each other, sixels use about 8 pixels per character
+	width := p.width
+	if p.mode == terminal.Sixel {
+		width *= 8
+	}
+	height := width * f.Height / f.Width
+	if height < 1 {
+		height = 1
+	}
+
+	if err := p.preview.Draw(p.development.Thumbnail(f, width, height)); err != nil {
+		log.Printf("drawing preview: %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"github.com/thijsheijden/go-raytracer/internal/preview"
+	"github.com/thijsheijden/go-raytracer/scene"
+	"log"
+	"net/http"
+)
+
+// runServe renders progressively and serves a live preview of the render in the browser
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := flags.String("listen", "localhost:8080", "address to serve the preview on")
+	scenePath := flags.String("scene", "", "JSON scene description to render, the default is a scene full of spheres")
+	sceneSeed := flags.Int64("scene-seed", 1, "seed used to generate the default scene")
+	settings := preview.Settings{}
+	flags.IntVar(&settings.SamplesPerPixel, "samples", nPixelSamples, "number of passes, each taking one sample per pixel")
+	flags.IntVar(&settings.MaxDepth, "max-depth", maxDepth, "maximum number of bounces of a path")
+	flags.IntVar(&settings.Threads, "threads", nThreads, "number of threads to split the work up")
+	flags.StringVar(&settings.Filter, "filter", "box", "pixel reconstruction filter: box, tent, gaussian, mitchell or lanczos")
+	flags.Float64Var(&settings.FilterRadius, "filter-radius", 0, "reconstruction filter radius in pixels, 0 uses the default of the filter")
+	flags.Float64Var(&settings.Exposure, "exposure", 0, "exposure compensation in stops (EV)")
+	flags.StringVar(&settings.ToneMapping, "tonemap", "clamp", "tone mapping operator: clamp, reinhard, reinhard-extended, aces or agx")
+	flags.Float64Var(&settings.WhitePoint, "white-point", 4, "luminance mapped to white by the reinhard-extended operator")
+	flags.Int64Var(&settings.Seed, "seed", 1, "seed for sampling")
+	flags.BoolVar(&settings.Spectral, "spectral", false, "trace a single wavelength per path, for dispersion in glass")
+	flags.Parse(args)
+
+	description := defaultScene(*sceneSeed)
+	if *scenePath != "" {
+		var err error
+		description, err = scene.Load(*scenePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	server := preview.New(description)
+	if err := server.Start(settings); err != nil {
+		return err
+	}
+
+	log.Printf("preview on http://%s", *listen)
+	return http.ListenAndServe(*listen, server.Handler())
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/thijsheijden/go-raytracer"
+	"github.com/thijsheijden/go-raytracer/render"
+	"github.com/thijsheijden/go-raytracer/scene"
+	"github.com/thijsheijden/go-raytracer/tonemap"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The state of a watched file, a missing file has the zero state
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// watch renders the scene file at a low sample count, and renders it again whenever the file
+// or one of the files it references changes. A render still running when a change comes in is cancelled.
+// Scenes which can not be loaded or built are reported and the next change is waited for, watch only returns an error
+// when the scene file is missing at the start or a render fails for another reason, like invalid options.
+func watch(ctx context.Context, scenePath string, options render.Options, development tonemap.Settings, interval time.Duration) error {
+	if _, err := os.Stat(scenePath); err != nil {
+		return err
+	}
+
+	cancel := func() {}
+	var done chan error // Receives the result of the running render, nil when none is running
+	stop := func() error {
+		cancel()
+		if done == nil {
+			return nil
+		}
+		err := <-done
+		done = nil
+		return err
+	}
+	defer stop()
+
+	var watched map[string]fileState
+	for {
+		if watched == nil || changed(watched) {
+			// Stop the render of the previous version before starting the new one
+			if err := stop(); err != nil {
+				return err
+			}
+
+			description, err := scene.Load(scenePath)
+			watched = states(append([]string{scenePath}, resolve(scenePath, description.Files())...))
+			if err != nil {
+				log.Printf("%v, waiting for the next change", err)
+			} else {
+				log.Printf("rendering %s", scenePath)
+				renderCtx, cancelRender := context.WithCancel(ctx)
+				cancel = cancelRender
+				result := make(chan error, 1)
+				done = result
+				go func() {
+					result <- renderWatched(renderCtx, description, options, development)
+				}()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-done:
+			done = nil
+			if err != nil {
+				return err
+			}
+		case <-time.After(interval):
+		}
+	}
+}
+
+// renderWatched renders the scene and writes the image, unless the render was cancelled
+// Problems the next change to the scene can fix are logged, only other render errors are returned.
+func renderWatched(ctx context.Context, description scene.Description, options render.Options, development tonemap.Settings) error {
+	loadedScene, err := description.Build()
+	if err != nil {
+		log.Printf("%v, waiting for the next change", err)
+		return nil
+	}
+
+	// Every render gets its own counts
+	if options.Stats != nil {
+		options.Stats = &render.Stats{}
+	}
+
+	start := time.Now()
+	fullFilm, err := raytracer.Render(ctx, &loadedScene, options)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	if err := raytracer.WriteImage(outputPath, raytracer.Develop(fullFilm, development)); err != nil {
+		log.Printf("%v, waiting for the next change", err)
+		return nil
+	}
+	log.Printf("rendered in %v, written to %s", time.Since(start).Round(time.Millisecond), outputPath)
+	if options.Stats != nil {
+		fmt.Fprint(os.Stderr, options.Stats.Summary())
+	}
+	return nil
+}
+
+// resolve makes the paths referenced by a scene file relative to the working directory
+func resolve(scenePath string, paths []string) []string {
+	resolved := make([]string, len(paths))
+	for i, path := range paths {
+		if filepath.IsAbs(path) {
+			resolved[i] = path
+		} else {
+			resolved[i] = filepath.Join(filepath.Dir(scenePath), path)
+		}
+	}
+	return resolved
+}
+
+// states returns the current state of every file
+func states(paths []string) map[string]fileState {
+	current := make(map[string]fileState, len(paths))
+	for _, path := range paths {
+		current[path] = stat(path)
+	}
+	return current
+}
+
+// changed checks whether any of the files changed since their state was taken
+func changed(watched map[string]fileState) bool {
+	for path, state := range watched {
+		if stat(path) != state {
+			return true
+		}
+	}
+	return false
+}
+
+func stat(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{modTime: info.ModTime(), size: info.Size()}
+}
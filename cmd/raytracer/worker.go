@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+	"github.com/thijsheijden/go-raytracer/render"
+	"log"
+	"net/http"
+)
+
+// runWorker serves tiles to a coordinator started with -workers
+func runWorker(args []string) error {
+	flags := flag.NewFlagSet("worker", flag.ExitOnError)
+	listen := flags.String("listen", "localhost:9001", "address to listen on")
+	threads := flags.Int("threads", nThreads, "number of threads to split a tile up")
+	flags.Parse(args)
+	if *threads <= 0 {
+		return &usageError{"-threads must be positive"}
+	}
+
+	log.Printf("worker listening on %s", *listen)
+	return http.ListenAndServe(*listen, &render.Worker{Threads: *threads})
+}
@@ -2,7 +2,6 @@ package color
 
 import (
 	"image/color"
-	"math"
 	"math/rand"
 )
 
@@ -50,32 +49,31 @@ func (c RGB) Add(c2 RGB) RGB {
 	}
 }
 
-// Average averages the color over n samples
-// Also adds gamma correction
-func (c RGB) Average(nSamples int) RGB {
-	scale := 1.0 / float32(nSamples)
-	return RGB{
-		R: float32(math.Sqrt(float64(c.R * scale))),
-		G: float32(math.Sqrt(float64(c.G * scale))),
-		B: float32(math.Sqrt(float64(c.B * scale))),
-	}
+// Scale multiplies all channels by s
+func (c RGB) Scale(s float32) RGB {
+	return c.Mul(s, s, s)
+}
+
+// Luminance returns the relative luminance of a linear color, using Rec. 709 primaries
+func (c RGB) Luminance() float64 {
+	return 0.2126*float64(c.R) + 0.7152*float64(c.G) + 0.0722*float64(c.B)
 }
 
 // Random returns a random color
-func Random() RGB {
+func Random(random *rand.Rand) RGB {
 	return RGB{
-		R: rand.Float32(),
-		G: rand.Float32(),
-		B: rand.Float32(),
+		R: random.Float32(),
+		G: random.Float32(),
+		B: random.Float32(),
 	}
 }
 
 // RandomInRange returns a color within the min, max range
-func RandomInRange(min, max float32) RGB {
+func RandomInRange(min, max float32, random *rand.Rand) RGB {
 	return RGB{
-		R: min + rand.Float32()*(max-min),
-		G: min + rand.Float32()*(max-min),
-		B: min + rand.Float32()*(max-min),
+		R: min + random.Float32()*(max-min),
+		G: min + random.Float32()*(max-min),
+		B: min + random.Float32()*(max-min),
 	}
 }
 
@@ -0,0 +1,174 @@
+// Package compare measures the difference between two images, like two renders of the same scene.
+package compare
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+)
+
+// ErrSizeMismatch is returned when comparing images of different sizes
+var ErrSizeMismatch = errors.New("images differ in size")
+
+// ErrEmptyImage is returned when comparing images without pixels, which have no mean error
+var ErrEmptyImage = errors.New("images have no pixels")
+
+// Result holds the difference between two images by several metrics
+type Result struct {
+	// Mean squared error of the sRGB values, between 0 and 1
+	MSE float64
+	// Root of the mean squared error
+	RMSE float64
+	// Peak signal to noise ratio in dB, infinite for identical images
+	PSNR float64
+	// Mean structural similarity of the luma, 1 for identical images
+	SSIM float64
+	// Mean perceptual error between 0 and 1, in the style of FLIP
+	FLIP float64
+
+	// Perceptual error per pixel
+	flip plane
+}
+
+// Images compares image b against the reference image a
+// It fails for images of different sizes or without pixels, which have no metrics.
+func Images(a, b image.Image) (*Result, error) {
+	if a.Bounds().Size() != b.Bounds().Size() {
+		return nil, fmt.Errorf("%w: %v and %v", ErrSizeMismatch, a.Bounds().Size(), b.Bounds().Size())
+	}
+	if a.Bounds().Empty() {
+		return nil, fmt.Errorf("%w: %v", ErrEmptyImage, a.Bounds().Size())
+	}
+
+	channelsA, channelsB := channels(a), channels(b)
+	result := &Result{
+		MSE:  mse(channelsA, channelsB),
+		SSIM: ssim(luma(channelsA), luma(channelsB)),
+		flip: flip(channelsA, channelsB),
+	}
+	result.RMSE = math.Sqrt(result.MSE)
+	result.PSNR = math.Inf(1)
+	if result.MSE > 0 {
+		result.PSNR = -10 * math.Log10(result.MSE)
+	}
+	result.FLIP = result.flip.mean()
+	return result, nil
+}
+
+// A single channel image of float64 values
+type plane struct {
+	width, height int
+	values        []float64
+}
+
+func newPlane(width, height int) plane {
+	return plane{
+		width:  width,
+		height: height,
+		values: make([]float64, width*height),
+	}
+}
+
+// at returns the value at (x, y), clamping the coordinates to the edges of the plane
+func (p plane) at(x, y int) float64 {
+	x = clamp(x, 0, p.width-1)
+	y = clamp(y, 0, p.height-1)
+	return p.values[y*p.width+x]
+}
+
+func (p plane) mean() float64 {
+	var sum float64
+	for _, v := range p.values {
+		sum += v
+	}
+	return sum / float64(len(p.values))
+}
+
+// channels splits the image into red, green and blue planes with values between 0 and 1
+func channels(img image.Image) [3]plane {
+	bounds := img.Bounds()
+	var c [3]plane
+	for i := range c {
+		c[i] = newPlane(bounds.Dx(), bounds.Dy())
+	}
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			i := y*bounds.Dx() + x
+			c[0].values[i] = float64(r) / 0xffff
+			c[1].values[i] = float64(g) / 0xffff
+			c[2].values[i] = float64(b) / 0xffff
+		}
+	}
+	return c
+}
+
+// luma returns the Rec. 601 luma of the channels
+func luma(c [3]plane) plane {
+	p := newPlane(c[0].width, c[0].height)
+	for i := range p.values {
+		p.values[i] = 0.299*c[0].values[i] + 0.587*c[1].values[i] + 0.114*c[2].values[i]
+	}
+	return p
+}
+
+// mse returns the mean squared error over all channels
+func mse(a, b [3]plane) float64 {
+	var sum float64
+	for c := range a {
+		for i := range a[c].values {
+			d := a[c].values[i] - b[c].values[i]
+			sum += d * d
+		}
+	}
+	return sum / float64(3*len(a[0].values))
+}
+
+// blur convolves the plane with a gaussian kernel, treating pixels outside it like the nearest edge pixel
+func blur(p plane, sigma float64) plane {
+	radius := int(math.Ceil(3 * sigma))
+	kernel := make([]float64, 2*radius+1)
+	var sum float64
+	for i := range kernel {
+		x := float64(i - radius)
+		kernel[i] = math.Exp(-x * x / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+
+	// The kernel is separable, blur the rows and then the columns
+	rows := newPlane(p.width, p.height)
+	for y := 0; y < p.height; y++ {
+		for x := 0; x < p.width; x++ {
+			var v float64
+			for i, k := range kernel {
+				v += k * p.at(x+i-radius, y)
+			}
+			rows.values[y*p.width+x] = v
+		}
+	}
+	blurred := newPlane(p.width, p.height)
+	for y := 0; y < p.height; y++ {
+		for x := 0; x < p.width; x++ {
+			var v float64
+			for i, k := range kernel {
+				v += k * rows.at(x, y+i-radius)
+			}
+			blurred.values[y*p.width+x] = v
+		}
+	}
+	return blurred
+}
+
+func clamp(x, min, max int) int {
+	if x < min {
+		return min
+	}
+	if x > max {
+		return max
+	}
+	return x
+}
@@ -0,0 +1,60 @@
+package compare
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func uniform(width, height int, c color.Gray) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = c.Y
+	}
+	return img
+}
+
+func TestIdentical(t *testing.T) {
+	img := uniform(16, 16, color.Gray{Y: 100})
+	img.SetGray(3, 5, color.Gray{Y: 250})
+
+	result, err := Images(img, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MSE != 0 || !math.IsInf(result.PSNR, 1) || math.Abs(result.SSIM-1) > 1e-9 || result.FLIP != 0 {
+		t.Errorf("identical images compare as %+v", result)
+	}
+}
+
+func TestUniformDifference(t *testing.T) {
+	result, err := Images(uniform(8, 8, color.Gray{Y: 0}), uniform(8, 8, color.Gray{Y: 51}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every channel is off by 51/255 = 0.2
+	if math.Abs(result.MSE-0.04) > 1e-9 || math.Abs(result.RMSE-0.2) > 1e-9 || math.Abs(result.PSNR-13.9794) > 1e-4 {
+		t.Errorf("got MSE %v, RMSE %v and PSNR %v", result.MSE, result.RMSE, result.PSNR)
+	}
+	if result.SSIM >= 1 || result.FLIP <= 0 {
+		t.Errorf("different images have SSIM %v and FLIP %v", result.SSIM, result.FLIP)
+	}
+}
+
+func TestSizeMismatch(t *testing.T) {
+	_, err := Images(uniform(8, 8, color.Gray{}), uniform(8, 9, color.Gray{}))
+	if !errors.Is(err, ErrSizeMismatch) {
+		t.Errorf("got %v, want ErrSizeMismatch", err)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	for _, size := range [][2]int{{0, 0}, {0, 8}, {8, 0}} {
+		_, err := Images(uniform(size[0], size[1], color.Gray{}), uniform(size[0], size[1], color.Gray{}))
+		if !errors.Is(err, ErrEmptyImage) {
+			t.Errorf("comparing %dx%d images: got %v, want ErrEmptyImage", size[0], size[1], err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package compare
+
+import (
+	"math"
+)
+
+// The perceptual error follows the structure of NVIDIA's FLIP, simplified for renders viewed at a normal distance.
+// Colors are compared in CIELAB after blurring them the way the eye blurs fine detail, the color error is then
+// amplified where edges and points differ between the images. The numbers are close to FLIP, not identical to it.
+
+// Parameters from the FLIP paper
+const (
+	flipColorExponent   = 0.7
+	flipFeatureExponent = 0.5
+	flipCutoff          = 0.4
+	flipCutoffError     = 0.95
+)
+
+// Standard deviation in pixels of the blur of the lightness and of the color channels, the eye resolves less color detail
+const (
+	flipSigmaLightness = 0.5
+	flipSigmaColor     = 1.5
+)
+
+// flip returns the perceptual error per pixel, between 0 and 1
+func flip(a, b [3]plane) plane {
+	labA, labB := lab(a), lab(b)
+	for c := range labA {
+		sigma := flipSigmaColor
+		if c == 0 {
+			sigma = flipSigmaLightness
+		}
+		labA[c], labB[c] = blur(labA[c], sigma), blur(labB[c], sigma)
+	}
+
+	edgesA, pointsA := features(labA[0])
+	edgesB, pointsB := features(labB[0])
+
+	// The largest color error, between pure green and pure blue
+	green, blue := labColor(0, 1, 0), labColor(0, 0, 1)
+	maxError := math.Pow(hyab(green, blue), flipColorExponent)
+
+	errorMap := newPlane(a[0].width, a[0].height)
+	for i := range errorMap.values {
+		colorError := math.Pow(hyab(
+			[3]float64{labA[0].values[i], labA[1].values[i], labA[2].values[i]},
+			[3]float64{labB[0].values[i], labB[1].values[i], labB[2].values[i]},
+		), flipColorExponent)
+
+		// Small errors are spread over most of the range, large ones are compressed towards 1
+		if colorError < flipCutoff*maxError {
+			colorError *= flipCutoffError / (flipCutoff * maxError)
+		} else {
+			colorError = flipCutoffError + (colorError-flipCutoff*maxError)/(maxError-flipCutoff*maxError)*(1-flipCutoffError)
+		}
+
+		featureError := math.Max(
+			math.Abs(edgesA.values[i]-edgesB.values[i]),
+			math.Abs(pointsA.values[i]-pointsB.values[i]),
+		)
+		featureError = math.Pow(math.Min(featureError/math.Sqrt2, 1), flipFeatureExponent)
+
+		errorMap.values[i] = math.Pow(math.Min(colorError, 1), 1-featureError)
+	}
+	return errorMap
+}
+
+// features returns the edge and point strength of a lightness plane, from its gradient and laplacian
+func features(lightness plane) (plane, plane) {
+	edges, points := newPlane(lightness.width, lightness.height), newPlane(lightness.width, lightness.height)
+	for y := 0; y < lightness.height; y++ {
+		for x := 0; x < lightness.width; x++ {
+			at := func(dx, dy int) float64 {
+				return lightness.at(x+dx, y+dy) / 100
+			}
+			gx := (at(1, -1) + 2*at(1, 0) + at(1, 1) - at(-1, -1) - 2*at(-1, 0) - at(-1, 1)) / 8
+			gy := (at(-1, 1) + 2*at(0, 1) + at(1, 1) - at(-1, -1) - 2*at(0, -1) - at(1, -1)) / 8
+			laplacian := at(1, 0) + at(-1, 0) + at(0, 1) + at(0, -1) - 4*at(0, 0)
+
+			i := y*lightness.width + x
+			edges.values[i] = math.Hypot(gx, gy)
+			points.values[i] = math.Abs(laplacian)
+		}
+	}
+	return edges, points
+}
+
+// hyab returns the HyAB distance between two CIELAB colors, which handles large differences better than euclidean distance
+func hyab(a, b [3]float64) float64 {
+	return math.Abs(a[0]-b[0]) + math.Hypot(a[1]-b[1], a[2]-b[2])
+}
+
+// lab converts sRGB planes to CIELAB planes
+func lab(c [3]plane) [3]plane {
+	var l [3]plane
+	for i := range l {
+		l[i] = newPlane(c[0].width, c[0].height)
+	}
+	for i := range c[0].values {
+		v := labColor(c[0].values[i], c[1].values[i], c[2].values[i])
+		l[0].values[i], l[1].values[i], l[2].values[i] = v[0], v[1], v[2]
+	}
+	return l
+}
+
+// labColor converts an sRGB color to CIELAB, relative to the D65 white point
+func labColor(r, g, b float64) [3]float64 {
+	r, g, b = linear(r), linear(g), linear(b)
+	x := (0.4124564*r + 0.3575761*g + 0.1804375*b) / 0.95047
+	y := 0.2126729*r + 0.7151522*g + 0.0721750*b
+	z := (0.0193339*r + 0.1191920*g + 0.9503041*b) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 216.0/24389.0 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27.0*t + 16) / 116
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+	return [3]float64{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+// linear undoes the sRGB transfer function
+func linear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
@@ -0,0 +1,43 @@
+package compare
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Points along the magma color map, from no error in black to the largest error in pale yellow
+var heatmapColors = []color.RGBA{
+	{0, 0, 4, 255},
+	{81, 18, 124, 255},
+	{183, 55, 121, 255},
+	{252, 137, 97, 255},
+	{252, 253, 191, 255},
+}
+
+// Heatmap returns an image of the perceptual error per pixel in false color
+func (r *Result) Heatmap() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, r.flip.width, r.flip.height))
+	for y := 0; y < r.flip.height; y++ {
+		for x := 0; x < r.flip.width; x++ {
+			img.SetRGBA(x, y, heatmapColor(r.flip.at(x, y)))
+		}
+	}
+	return img
+}
+
+// heatmapColor maps an error between 0 and 1 onto the color map
+func heatmapColor(e float64) color.RGBA {
+	position := math.Max(0, math.Min(e, 1)) * float64(len(heatmapColors)-1)
+	i := int(position)
+	if i == len(heatmapColors)-1 {
+		return heatmapColors[i]
+	}
+
+	t := position - float64(i)
+	from, to := heatmapColors[i], heatmapColors[i+1]
+	mix := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + t*(float64(b)-float64(a))))
+	}
+	return color.RGBA{mix(from.R, to.R), mix(from.G, to.G), mix(from.B, to.B), 255}
+}
@@ -0,0 +1,33 @@
+package compare
+
+// Constants stabilising the division for dark and flat areas, from the original SSIM paper
+const (
+	ssimC1 = 0.01 * 0.01
+	ssimC2 = 0.03 * 0.03
+)
+
+// ssim returns the mean structural similarity of two planes, using a gaussian window with a standard deviation of 1.5 pixels
+func ssim(a, b plane) float64 {
+	product := func(x, y plane) plane {
+		p := newPlane(x.width, x.height)
+		for i := range p.values {
+			p.values[i] = x.values[i] * y.values[i]
+		}
+		return p
+	}
+
+	const sigma = 1.5
+	meanA, meanB := blur(a, sigma), blur(b, sigma)
+	meanAA, meanBB, meanAB := blur(product(a, a), sigma), blur(product(b, b), sigma), blur(product(a, b), sigma)
+
+	var sum float64
+	for i := range a.values {
+		muA, muB := meanA.values[i], meanB.values[i]
+		varianceA := meanAA.values[i] - muA*muA
+		varianceB := meanBB.values[i] - muB*muB
+		covariance := meanAB.values[i] - muA*muB
+		sum += (2*muA*muB + ssimC1) * (2*covariance + ssimC2) /
+			((muA*muA + muB*muB + ssimC1) * (varianceA + varianceB + ssimC2))
+	}
+	return sum / float64(len(a.values))
+}
@@ -0,0 +1,41 @@
+// Basic builds a small scene in code, renders it and saves it as a PNG.
+package main
+
+import (
+	"context"
+	"github.com/thijsheijden/go-raytracer"
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/object"
+	"github.com/thijsheijden/go-raytracer/scene"
+	"github.com/thijsheijden/go-raytracer/tonemap"
+	"github.com/thijsheijden/go-raytracer/vector"
+	"log"
+)
+
+func main() {
+	const aspectRatio = 3.0 / 2.0
+	camera := scene.NewCamera(vector.New(0, 0.5, 2), vector.New(0, 0, -1), vector.New(0, 1, 0), 40, aspectRatio, 1)
+	s := scene.New(camera, aspectRatio, 300)
+
+	// A red ball on a grey floor, flanked by glass and gold
+	s.Spheres = append(s.Spheres,
+		object.NewSphere(vector.New(0, -100.5, -1), 100, object.Lambertian(color.New(0.5, 0.5, 0.5))),
+		object.NewSphere(vector.New(0, 0, -1), 0.5, object.Lambertian(color.New(0.7, 0.1, 0.1))),
+		object.NewSphere(vector.New(-1, 0, -1), 0.5, object.Dielectric(1.5)),
+		object.NewSphere(vector.New(1, 0, -1), 0.5, object.FuzzyMetal(color.New(0.8, 0.6, 0.2), 0.1)),
+	)
+
+	options := raytracer.DefaultOptions()
+	options.SamplesPerPixel = 50
+
+	f, err := raytracer.Render(context.Background(), &s, options)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	settings := tonemap.Default()
+	settings.Operator = tonemap.ACES()
+	if err := raytracer.WriteImage("basic.png", raytracer.Develop(f, settings)); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,40 @@
+// Progressive loads a scene description and renders it progressively for a fixed amount of time,
+// saving the image after every pass.
+package main
+
+import (
+	"context"
+	"github.com/thijsheijden/go-raytracer"
+	"github.com/thijsheijden/go-raytracer/film"
+	"github.com/thijsheijden/go-raytracer/render"
+	"github.com/thijsheijden/go-raytracer/scene"
+	"github.com/thijsheijden/go-raytracer/tonemap"
+	"log"
+	"time"
+)
+
+func main() {
+	description, err := scene.Load("scenes/three-balls.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	s, err := description.Build()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Stop after ten seconds, whatever was rendered by then is kept
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	options := raytracer.DefaultOptions()
+	options.SamplesPerPixel = 1000
+	renderer := render.New(&s, options)
+
+	_, passes := renderer.Progressive(ctx, func(pass int, f *film.Film) {
+		if err := raytracer.WriteImage("progressive.png", raytracer.Develop(f, tonemap.Default())); err != nil {
+			log.Fatal(err)
+		}
+	})
+	log.Printf("rendered %d passes", passes)
+}
@@ -0,0 +1,198 @@
+package film
+
+import (
+	"fmt"
+	"github.com/thijsheijden/go-raytracer/color"
+	"image"
+	"math"
+)
+
+// Film accumulates filtered radiance samples for (a part of) an image
+type Film struct {
+	Width, Height int             // Size of the full image in pixels
+	Bounds        image.Rectangle // The pixels stored in this film
+	filter        Filter
+	pixels        []pixel
+}
+
+// A pixel holds the weighted sum of all samples splatted onto it
+type pixel struct {
+	r, g, b float64
+	weight  float64
+	samples int64 // Number of samples taken within this pixel
+}
+
+// New creates an empty film for an image of width by height pixels
+func New(width, height int, filter Filter) *Film {
+	return newFilm(width, height, image.Rect(0, 0, width, height), filter)
+}
+
+func newFilm(width, height int, bounds image.Rectangle, filter Filter) *Film {
+	return &Film{
+		Width:  width,
+		Height: height,
+		Bounds: bounds,
+		filter: filter,
+		pixels: make([]pixel, bounds.Dx()*bounds.Dy()),
+	}
+}
+
+// Tile creates an empty film for rendering the pixels within bounds
+// The tile is padded by the filter radius, so samples near its edge can splat onto neighbouring pixels
+func (f *Film) Tile(bounds image.Rectangle) *Film {
+	pad := int(math.Ceil(f.filter.Radius()))
+	padded := image.Rect(bounds.Min.X-pad, bounds.Min.Y-pad, bounds.Max.X+pad, bounds.Max.Y+pad)
+	return newFilm(f.Width, f.Height, padded.Intersect(f.Bounds), f.filter)
+}
+
+// NewTile creates an empty tile film for the pixels within bounds of an image of width by height pixels
+// It is the same as New(width, height, filter).Tile(bounds), without allocating the full film
+func NewTile(width, height int, bounds image.Rectangle, filter Filter) *Film {
+	return (&Film{Width: width, Height: height, Bounds: image.Rect(0, 0, width, height), filter: filter}).Tile(bounds)
+}
+
+// AddSample splats a sample taken at raster position (x, y) onto all pixels within the filter radius
+func (f *Film) AddSample(x, y float64, c color.RGB) {
+	radius := f.filter.Radius()
+
+	// Pixel centers lie at half-integer raster positions
+	x0 := max(int(math.Ceil(x-0.5-radius)), f.Bounds.Min.X)
+	x1 := min(int(math.Floor(x-0.5+radius)), f.Bounds.Max.X-1)
+	y0 := max(int(math.Ceil(y-0.5-radius)), f.Bounds.Min.Y)
+	y1 := min(int(math.Floor(y-0.5+radius)), f.Bounds.Max.Y-1)
+
+	// Count the sample for the pixel it was taken in
+	if sample := image.Pt(int(math.Floor(x)), int(math.Floor(y))); sample.In(f.Bounds) {
+		f.pixels[f.offset(sample.X, sample.Y)].samples++
+	}
+
+	for py := y0; py <= y1; py++ {
+		for px := x0; px <= x1; px++ {
+			weight := f.filter.Evaluate(float64(px)+0.5-x, float64(py)+0.5-y)
+			if weight == 0 {
+				continue
+			}
+
+			p := &f.pixels[f.offset(px, py)]
+			p.r += weight * float64(c.R)
+			p.g += weight * float64(c.G)
+			p.b += weight * float64(c.B)
+			p.weight += weight
+		}
+	}
+}
+
+// Merge adds the samples of tile to this film
+func (f *Film) Merge(tile *Film) {
+	r := tile.Bounds.Intersect(f.Bounds)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			src := tile.pixels[tile.offset(x, y)]
+			dst := &f.pixels[f.offset(x, y)]
+			dst.r += src.r
+			dst.g += src.g
+			dst.b += src.b
+			dst.weight += src.weight
+			dst.samples += src.samples
+		}
+	}
+}
+
+// Reset discards all accumulated samples
+func (f *Film) Reset() {
+	for i := range f.pixels {
+		f.pixels[i] = pixel{}
+	}
+}
+
+// Pixel returns the reconstructed linear color of pixel (x, y)
+// Filters with negative lobes can produce negative values, these are clamped to zero
+func (f *Film) Pixel(x, y int) color.RGB {
+	p := f.pixels[f.offset(x, y)]
+	if p.weight <= 0 {
+		return color.New(0, 0, 0)
+	}
+	return color.New(
+		float32(math.Max(0, p.r/p.weight)),
+		float32(math.Max(0, p.g/p.weight)),
+		float32(math.Max(0, p.b/p.weight)),
+	)
+}
+
+// Samples returns the number of samples taken within pixel (x, y)
+func (f *Film) Samples(x, y int) int64 {
+	return f.pixels[f.offset(x, y)].samples
+}
+
+// TotalSamples returns the number of samples taken within all pixels of the film
+func (f *Film) TotalSamples() int64 {
+	var total int64
+	for _, p := range f.pixels {
+		total += p.samples
+	}
+	return total
+}
+
+// State is a serializable copy of all samples accumulated by a film
+type State struct {
+	Width, Height int
+	Bounds        image.Rectangle
+	Sums          []float64 // Weighted red, green and blue sums and the weight sum of every pixel
+	Samples       []int64   // Number of samples taken within every pixel
+}
+
+// State returns a copy of the accumulated samples
+func (f *Film) State() State {
+	state := State{
+		Width:   f.Width,
+		Height:  f.Height,
+		Bounds:  f.Bounds,
+		Sums:    make([]float64, 0, 4*len(f.pixels)),
+		Samples: make([]int64, 0, len(f.pixels)),
+	}
+	for _, p := range f.pixels {
+		state.Sums = append(state.Sums, p.r, p.g, p.b, p.weight)
+		state.Samples = append(state.Samples, p.samples)
+	}
+	return state
+}
+
+// Restore replaces the accumulated samples by those in state, which must have the same size as the film
+func (f *Film) Restore(state State) error {
+	if state.Width != f.Width || state.Height != f.Height || state.Bounds != f.Bounds {
+		return fmt.Errorf("film state of %dx%d pixels at %v does not match film of %dx%d pixels at %v",
+			state.Width, state.Height, state.Bounds, f.Width, f.Height, f.Bounds)
+	}
+	if len(state.Sums) != 4*len(f.pixels) || len(state.Samples) != len(f.pixels) {
+		return fmt.Errorf("film state holds %d sums and %d sample counts for %d pixels", len(state.Sums), len(state.Samples), len(f.pixels))
+	}
+
+	for i := range f.pixels {
+		f.pixels[i] = pixel{
+			r:       state.Sums[4*i],
+			g:       state.Sums[4*i+1],
+			b:       state.Sums[4*i+2],
+			weight:  state.Sums[4*i+3],
+			samples: state.Samples[i],
+		}
+	}
+	return nil
+}
+
+func (f *Film) offset(x, y int) int {
+	return (y-f.Bounds.Min.Y)*f.Bounds.Dx() + (x - f.Bounds.Min.X)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,218 @@
+package film
+
+import (
+	"fmt"
+	"math"
+)
+
+// Filter is a pixel reconstruction filter, weighting a sample by its offset from a pixel center
+type Filter interface {
+	// Name returns the name of the filter, as accepted by FilterByName
+	Name() string
+	// Radius returns the radius of the filter support in pixels
+	Radius() float64
+	// Evaluate returns the weight of a sample at offset (x, y) from the pixel center
+	Evaluate(x, y float64) float64
+}
+
+// Filters lists the names accepted by FilterByName
+var Filters = []string{"box", "tent", "gaussian", "mitchell", "lanczos"}
+
+// Default radius of each filter in pixels, wide enough for its shape: the tent and gaussian reach zero at their
+// radius, the Mitchell-Netravali cubic spans 2 pixels on each side and Lanczos has its 3 lobes a pixel apart
+var defaultRadii = map[string]float64{
+	"box":      0.5,
+	"tent":     1,
+	"gaussian": 1.5,
+	"mitchell": 2,
+	"lanczos":  3,
+}
+
+// FilterByName returns the filter with the given name and radius, using default parameters
+// A radius of 0 uses the default radius of the filter.
+func FilterByName(name string, radius float64) (Filter, error) {
+	if radius < 0 {
+		return nil, fmt.Errorf("filter radius can not be negative, got %v", radius)
+	}
+	if radius == 0 {
+		radius = defaultRadii[name]
+	}
+
+	switch name {
+	case "box":
+		return Box(radius), nil
+	case "tent":
+		return Tent(radius), nil
+	case "gaussian":
+		return Gaussian(radius, 2), nil
+	case "mitchell":
+		return Mitchell(radius, 1.0/3.0, 1.0/3.0), nil
+	case "lanczos":
+		return Lanczos(radius, 3), nil
+	}
+	return nil, fmt.Errorf("unknown filter %q", name)
+}
+
+// Box filter, weighting every sample within the radius equally
+type box struct {
+	radius float64
+}
+
+// Box returns a box filter. A radius of 0.5 averages the samples within each pixel.
+func Box(radius float64) Filter {
+	return box{
+		radius: radius,
+	}
+}
+
+func (f box) Name() string {
+	return "box"
+}
+
+func (f box) Radius() float64 {
+	return f.radius
+}
+
+func (f box) Evaluate(x, y float64) float64 {
+	if math.Abs(x) > f.radius || math.Abs(y) > f.radius {
+		return 0
+	}
+	return 1
+}
+
+// Tent filter, falling off linearly from the center
+type tent struct {
+	radius float64
+}
+
+// Tent returns a tent (triangle) filter
+func Tent(radius float64) Filter {
+	return tent{
+		radius: radius,
+	}
+}
+
+func (f tent) Name() string {
+	return "tent"
+}
+
+func (f tent) Radius() float64 {
+	return f.radius
+}
+
+func (f tent) Evaluate(x, y float64) float64 {
+	return math.Max(0, f.radius-math.Abs(x)) * math.Max(0, f.radius-math.Abs(y))
+}
+
+// Gaussian filter, shifted down so it reaches zero at the radius
+type gaussian struct {
+	radius float64
+	alpha  float64
+	edge   float64
+}
+
+// Gaussian returns a gaussian filter, alpha controls the falloff
+func Gaussian(radius, alpha float64) Filter {
+	return gaussian{
+		radius: radius,
+		alpha:  alpha,
+		edge:   math.Exp(-alpha * radius * radius),
+	}
+}
+
+func (f gaussian) Name() string {
+	return "gaussian"
+}
+
+func (f gaussian) Radius() float64 {
+	return f.radius
+}
+
+func (f gaussian) Evaluate(x, y float64) float64 {
+	return f.gaussian(x) * f.gaussian(y)
+}
+
+func (f gaussian) gaussian(d float64) float64 {
+	return math.Max(0, math.Exp(-f.alpha*d*d)-f.edge)
+}
+
+// Mitchell-Netravali filter, B and C trade blurring against ringing
+type mitchell struct {
+	radius float64
+	b, c   float64
+}
+
+// Mitchell returns a Mitchell-Netravali filter. B = C = 1/3 is the recommended default.
+func Mitchell(radius, b, c float64) Filter {
+	return mitchell{
+		radius: radius,
+		b:      b,
+		c:      c,
+	}
+}
+
+func (f mitchell) Name() string {
+	return "mitchell"
+}
+
+func (f mitchell) Radius() float64 {
+	return f.radius
+}
+
+func (f mitchell) Evaluate(x, y float64) float64 {
+	return f.mitchell(x/f.radius) * f.mitchell(y/f.radius)
+}
+
+// mitchell evaluates the 1D filter for d in [-1, 1], which is mapped onto the [-2, 2] support of the cubic
+func (f mitchell) mitchell(d float64) float64 {
+	d = math.Abs(2 * d)
+	b, c := f.b, f.c
+	if d > 2 {
+		return 0
+	}
+	if d > 1 {
+		return ((-b-6*c)*d*d*d + (6*b+30*c)*d*d + (-12*b-48*c)*d + (8*b + 24*c)) / 6
+	}
+	return ((12-9*b-6*c)*d*d*d + (-18+12*b+6*c)*d*d + (6 - 2*b)) / 6
+}
+
+// Lanczos windowed sinc filter
+type lanczos struct {
+	radius float64
+	tau    float64
+}
+
+// Lanczos returns a windowed sinc filter, tau is the number of sinc lobes within the radius
+func Lanczos(radius, tau float64) Filter {
+	return lanczos{
+		radius: radius,
+		tau:    tau,
+	}
+}
+
+func (f lanczos) Name() string {
+	return "lanczos"
+}
+
+func (f lanczos) Radius() float64 {
+	return f.radius
+}
+
+func (f lanczos) Evaluate(x, y float64) float64 {
+	return f.windowedSinc(x) * f.windowedSinc(y)
+}
+
+func (f lanczos) windowedSinc(d float64) float64 {
+	d = math.Abs(d / f.radius)
+	if d > 1 {
+		return 0
+	}
+	return sinc(d*f.tau) * sinc(d)
+}
+
+func sinc(x float64) float64 {
+	if x < 1e-5 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
@@ -0,0 +1,44 @@
+package film
+
+import (
+	"testing"
+)
+
+// TestFilterDefaults checks that every named filter has a sensible shape at its default radius
+func TestFilterDefaults(t *testing.T) {
+	for _, name := range Filters {
+		f, err := FilterByName(name, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		// Integrate a slice through the center, separating the positive weights from the negative lobes
+		const steps = 10000
+		var positive, negative float64
+		dx := 2 * f.Radius() / steps
+		for i := 0; i < steps; i++ {
+			x := -f.Radius() + (float64(i)+0.5)*dx
+			w := f.Evaluate(x, 0)
+			if w > f.Evaluate(0, 0) {
+				t.Errorf("%s: weight %v at %v is above the weight at the center", name, w, x)
+				break
+			}
+			if w > 0 {
+				positive += w * dx
+			} else {
+				negative -= w * dx
+			}
+		}
+		if positive <= 0 || negative > 0.2*positive {
+			t.Errorf("%s: radius %v gives %v positive and %v negative weight, want mostly positive", name, f.Radius(), positive, negative)
+		}
+
+		// The center lobe is at least a pixel wide, a squashed filter crosses zero within the pixel
+		for x := 0.0; x < 0.5; x += 0.01 {
+			if f.Evaluate(x, 0) <= 0 {
+				t.Errorf("%s: radius %v gives no weight at %v pixels from the center", name, f.Radius(), x)
+				break
+			}
+		}
+	}
+}
@@ -1,3 +1,3 @@
-module raytracer
+module github.com/thijsheijden/go-raytracer
 
 go 1.17
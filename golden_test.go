@@ -0,0 +1,122 @@
+package raytracer
+
+import (
+	"context"
+	"flag"
+	"github.com/thijsheijden/go-raytracer/compare"
+	"github.com/thijsheijden/go-raytracer/film"
+	"github.com/thijsheijden/go-raytracer/scene"
+	"github.com/thijsheijden/go-raytracer/tonemap"
+	"github.com/thijsheijden/go-raytracer/vector"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "render the golden images again instead of comparing against them")
+
+// Renders with a PSNR below this, in dB, fail
+// Renders on the same platform are identical, the margin absorbs floating point differences between platforms.
+const minPSNR = 35
+
+// Small, deterministic versions of the built-in scenes and of the scene files in scenes
+var goldenScenes = []struct {
+	name        string
+	description scene.Description
+	path        string // Scene file to render instead of the description
+}{
+	{"three-balls", goldenScene("threeBalls", vector.New(0, 0, 1), vector.New(0, 0, -1), 60), ""},
+	{"glass-balls", goldenScene("glassBalls", vector.New(0, 0.3, 1), vector.New(0, 0, -1), 60), ""},
+	{"lots-of-spheres", goldenScene("lotsOfSpheres", vector.New(13, 2, 3), vector.New(0, 0, 0), 20), ""},
+	{"principled", scene.Description{}, filepath.Join("scenes", "principled.json")},
+}
+
+func goldenScene(preset string, position, lookAt vector.Vector, fov float64) scene.Description {
+	return scene.Description{
+		Camera: scene.CameraDescription{
+			Position:    position,
+			LookAt:      lookAt,
+			VUp:         vector.New(0, 1, 0),
+			VerticalFOV: fov,
+			FocalLength: 1,
+		},
+		AspectRatio: 16.0 / 9.0,
+		ImageWidth:  96,
+		Preset:      preset,
+		Seed:        1,
+	}
+}
+
+func TestGolden(t *testing.T) {
+	for _, golden := range goldenScenes {
+		t.Run(golden.name, func(t *testing.T) {
+			description := golden.description
+			if golden.path != "" {
+				var err error
+				description, err = scene.Load(golden.path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				description.ImageWidth = 96
+			}
+
+			s, err := description.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := Render(context.Background(), &s, Options{
+				SamplesPerPixel: 16,
+				MaxDepth:        10,
+				Threads:         runtime.NumCPU(),
+				Filter:          film.Box(0.5),
+				Seed:            1,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := Develop(f, tonemap.Default())
+
+			path := filepath.Join("testdata", "golden", golden.name+".png")
+			if *update {
+				if err := WriteImage(path, actual); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			expected, err := readPNG(path)
+			if err != nil {
+				t.Fatalf("%v, run go test -update to create it", err)
+			}
+			result, err := compare.Images(expected, actual)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.PSNR < minPSNR {
+				actualPath := filepath.Join("testdata", "golden", golden.name+".actual.png")
+				diffPath := filepath.Join("testdata", "golden", golden.name+".diff.png")
+				if err := WriteImage(actualPath, actual); err != nil {
+					t.Error(err)
+				}
+				if err := WriteImage(diffPath, result.Heatmap()); err != nil {
+					t.Error(err)
+				}
+				t.Errorf("PSNR %.1f dB is below %v dB, see %s and %s", result.PSNR, minPSNR, actualPath, diffPath)
+			}
+		})
+	}
+}
+
+func readPNG(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return png.Decode(file)
+}
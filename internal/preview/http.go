@@ -0,0 +1,116 @@
+package preview
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Handler returns the HTTP handler serving the preview page and its endpoints
+//
+//	GET  /           the preview page
+//	GET  /image.png  the latest image
+//	GET  /status     the progress as JSON
+//	GET  /events     server-sent events with the progress, sent whenever the image changes
+//	POST /stop       stops the render
+//	POST /restart    restarts the render, the JSON body overrides the current settings
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handlePage)
+	mux.HandleFunc("/image.png", s.handleImage)
+	mux.HandleFunc("/status", s.handleStatus)
+	mux.HandleFunc("/events", s.handleEvents)
+	mux.HandleFunc("/stop", s.handleStop)
+	mux.HandleFunc("/restart", s.handleRestart)
+	return mux
+}
+
+func (s *Server) handlePage(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/" {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, page)
+}
+
+func (s *Server) handleImage(w http.ResponseWriter, req *http.Request) {
+	img, err := s.Image()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := png.Encode(w, img); err != nil {
+		log.Printf("sending image: %v", err)
+	}
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.Status())
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+
+	// Poll the status and send it whenever it changed
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	version := -1
+	for {
+		if status := s.Status(); status.Version != version {
+			version = status.Version
+			data, err := json.Marshal(status)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) handleStop(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "use POST to stop the render", http.StatusMethodNotAllowed)
+		return
+	}
+	s.Stop()
+	s.handleStatus(w, req)
+}
+
+func (s *Server) handleRestart(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "use POST to restart the render", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Fields missing from the body keep their current value
+	settings := s.Status().Settings
+	if err := json.NewDecoder(req.Body).Decode(&settings); err != nil {
+		http.Error(w, fmt.Sprintf("decoding settings: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := s.Start(settings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.handleStatus(w, req)
+}
@@ -0,0 +1,86 @@
+package preview
+
+// page is the preview page, it follows the render through the /events stream
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Ray tracer preview</title>
+<style>
+body { font-family: sans-serif; background: #222; color: #ddd; margin: 1em; }
+img { max-width: 100%; image-rendering: pixelated; border: 1px solid #444; }
+table { border-collapse: collapse; margin: 1em 0; }
+td { padding: 0.2em 1em 0.2em 0; }
+input, select, button { margin: 0.2em; }
+</style>
+</head>
+<body>
+<img id="image" src="image.png" alt="render">
+<table>
+<tr><td>State</td><td id="state"></td></tr>
+<tr><td>Pass</td><td id="pass"></td></tr>
+<tr><td>Elapsed</td><td id="elapsed"></td></tr>
+<tr><td>Samples per second</td><td id="rate"></td></tr>
+</table>
+<form id="settings">
+<label>Samples per pixel <input name="samplesPerPixel" type="number" min="1"></label>
+<label>Max depth <input name="maxDepth" type="number" min="1"></label>
+<label>Filter <select name="filter">
+<option>box</option><option>tent</option><option>gaussian</option><option>mitchell</option><option>lanczos</option>
+</select></label>
+<label>Filter radius <input name="filterRadius" type="number" step="0.1" min="0" title="0 uses the default of the filter"></label>
+<label>Exposure <input name="exposure" type="number" step="0.5"></label>
+<label>Tone mapping <select name="toneMapping">
+<option>clamp</option><option>reinhard</option><option>reinhard-extended</option><option>aces</option><option>agx</option>
+</select></label>
+<label>Seed <input name="seed" type="number"></label>
+<label>Spectral <input name="spectral" type="checkbox"></label>
+<br>
+<button type="submit">Restart</button>
+<button type="button" id="stop">Stop</button>
+<span id="error"></span>
+</form>
+<script>
+const form = document.getElementById("settings");
+const numbers = ["samplesPerPixel", "maxDepth", "filterRadius", "exposure", "seed"];
+let filled = false;
+
+function show(status) {
+	document.getElementById("image").src = "image.png?v=" + status.version;
+	document.getElementById("state").textContent = status.running ? "rendering" : "stopped";
+	document.getElementById("pass").textContent = status.pass + " / " + status.settings.samplesPerPixel;
+	document.getElementById("elapsed").textContent = status.elapsedSeconds.toFixed(1) + " s";
+	document.getElementById("rate").textContent = Math.round(status.samplesPerSecond).toLocaleString();
+	if (!filled) {
+		for (const [name, value] of Object.entries(status.settings)) {
+			const element = form.elements[name];
+			if (!element) continue;
+			if (element.type === "checkbox") element.checked = value;
+			else element.value = value;
+		}
+		filled = true;
+	}
+}
+
+async function post(path, body) {
+	const response = await fetch(path, { method: "POST", body: body });
+	document.getElementById("error").textContent = response.ok ? "" : await response.text();
+}
+
+form.addEventListener("submit", (event) => {
+	event.preventDefault();
+	const settings = {};
+	for (const element of form.elements) {
+		if (!element.name) continue;
+		if (element.type === "checkbox") settings[element.name] = element.checked;
+		else settings[element.name] = numbers.includes(element.name) ? Number(element.value) : element.value;
+	}
+	post("restart", JSON.stringify(settings));
+});
+document.getElementById("stop").addEventListener("click", () => post("stop"));
+
+new EventSource("events").onmessage = (event) => show(JSON.parse(event.data));
+</script>
+</body>
+</html>
+`
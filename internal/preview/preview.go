@@ -0,0 +1,170 @@
+package preview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/thijsheijden/go-raytracer/film"
+	"github.com/thijsheijden/go-raytracer/render"
+	"github.com/thijsheijden/go-raytracer/scene"
+	"github.com/thijsheijden/go-raytracer/tonemap"
+	"image"
+	"sync"
+	"time"
+)
+
+// Settings are the parameters of a preview render, they can be changed when restarting it
+type Settings struct {
+	SamplesPerPixel int     `json:"samplesPerPixel"`
+	MaxDepth        int     `json:"maxDepth"`
+	Threads         int     `json:"threads"`
+	Filter          string  `json:"filter"`
+	FilterRadius    float64 `json:"filterRadius"`
+	Exposure        float64 `json:"exposure"`
+	ToneMapping     string  `json:"toneMapping"`
+	WhitePoint      float64 `json:"whitePoint"`
+	Seed            int64   `json:"seed"`
+	Spectral        bool    `json:"spectral"`
+}
+
+// Status reports the progress of the current render
+type Status struct {
+	Settings         Settings `json:"settings"`
+	Running          bool     `json:"running"`
+	Version          int      `json:"version"` // Increases every time the image changes
+	Pass             int      `json:"pass"`
+	Samples          int64    `json:"samples"`
+	ElapsedSeconds   float64  `json:"elapsedSeconds"`
+	SamplesPerSecond float64  `json:"samplesPerSecond"`
+}
+
+// A Server runs a progressive render of a scene and serves its progress over HTTP
+type Server struct {
+	scene scene.Description
+
+	control sync.Mutex // Serialises starting and stopping renders
+
+	mu      sync.Mutex
+	status  Status
+	image   *image.RGBA
+	started time.Time
+	cancel  context.CancelFunc
+	done    chan struct{} // Closed when the current render has stopped
+}
+
+// New creates a server for the scene, no render is started yet
+func New(description scene.Description) *Server {
+	return &Server{
+		scene: description,
+	}
+}
+
+// Start stops the current render and starts a new one with the given settings
+// The settings are checked first, when they are invalid the current render keeps running.
+func (s *Server) Start(settings Settings) error {
+	loadedScene, err := s.scene.Build()
+	if err != nil {
+		return err
+	}
+	if settings.SamplesPerPixel <= 0 || settings.MaxDepth <= 0 || settings.Threads <= 0 {
+		return errors.New("samples per pixel, max depth and threads must be positive")
+	}
+	filter, err := film.FilterByName(settings.Filter, settings.FilterRadius)
+	if err != nil {
+		return err
+	}
+	operator, err := tonemap.OperatorByName(settings.ToneMapping, settings.WhitePoint)
+	if err != nil {
+		return err
+	}
+	development := tonemap.Settings{Exposure: settings.Exposure, Operator: operator}
+
+	s.control.Lock()
+	defer s.control.Unlock()
+	s.stop()
+
+	renderer := render.New(&loadedScene, render.Options{
+		SamplesPerPixel: settings.SamplesPerPixel,
+		MaxDepth:        settings.MaxDepth,
+		Threads:         settings.Threads,
+		Filter:          filter,
+		Seed:            settings.Seed,
+		Spectral:        settings.Spectral,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	s.mu.Lock()
+	s.cancel = cancel
+	s.done = done
+	s.started = time.Now()
+	s.status = Status{
+		Settings: settings,
+		Running:  true,
+		Version:  s.status.Version + 1,
+	}
+	s.image = image.NewRGBA(image.Rect(0, 0, loadedScene.ImageWidth, loadedScene.ImageHeight))
+	s.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		f, pass := renderer.Progressive(ctx, func(pass int, f *film.Film) {
+			s.update(development.Image(f), pass, f.TotalSamples(), true)
+		})
+		s.update(development.Image(f), pass, f.TotalSamples(), false)
+	}()
+	return nil
+}
+
+// Stop stops the current render and waits until it has finished, the last image stays available
+func (s *Server) Stop() {
+	s.control.Lock()
+	defer s.control.Unlock()
+	s.stop()
+}
+
+func (s *Server) stop() {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// Status returns the progress of the current render
+func (s *Server) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Image returns the latest image of the current render
+func (s *Server) Image() (*image.RGBA, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.image == nil {
+		return nil, fmt.Errorf("no render started")
+	}
+	return s.image, nil
+}
+
+// update stores the latest image and progress
+func (s *Server) update(img *image.RGBA, pass int, samples int64, running bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elapsed := time.Since(s.started).Seconds()
+	s.image = img
+	s.status.Running = running
+	s.status.Version++
+	s.status.Pass = pass
+	s.status.Samples = samples
+	s.status.ElapsedSeconds = elapsed
+	if elapsed > 0 {
+		s.status.SamplesPerSecond = float64(samples) / elapsed
+	}
+}
@@ -0,0 +1,89 @@
+package terminal
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// writeSixel writes the image as sixel graphics, using a 6x6x6 color cube as palette
+func writeSixel(w io.Writer, img image.Image) {
+	b := img.Bounds()
+
+	// Start sixel data with square pixels and declare the image size
+	fmt.Fprintf(w, "\x1bP0;1;0q\"1;1;%d;%d", b.Dx(), b.Dy())
+	for i := 0; i < 216; i++ {
+		r, g, bl := i/36, (i/6)%6, i%6
+		fmt.Fprintf(w, "#%d;2;%d;%d;%d", i, r*20, g*20, bl*20)
+	}
+
+	// Palette index of every pixel
+	indices := make([]uint8, b.Dx()*b.Dy())
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			c := color.RGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.RGBA)
+			indices[y*b.Dx()+x] = uint8(36*quantize(c.R) + 6*quantize(c.G) + quantize(c.B))
+		}
+	}
+
+	// Every band of six rows is drawn color by color, returning to the start of the band in between
+	bits := make([]byte, b.Dx())
+	for band := 0; band < b.Dy(); band += 6 {
+		var used [216]bool
+		for y := band; y < band+6 && y < b.Dy(); y++ {
+			for x := 0; x < b.Dx(); x++ {
+				used[indices[y*b.Dx()+x]] = true
+			}
+		}
+
+		first := true
+		for c := range used {
+			if !used[c] {
+				continue
+			}
+			for x := range bits {
+				bits[x] = 0
+				for row := 0; row < 6 && band+row < b.Dy(); row++ {
+					if int(indices[(band+row)*b.Dx()+x]) == c {
+						bits[x] |= 1 << row
+					}
+				}
+			}
+
+			if !first {
+				fmt.Fprint(w, "$")
+			}
+			first = false
+			fmt.Fprintf(w, "#%d", c)
+			writeRuns(w, bits)
+		}
+		fmt.Fprint(w, "-")
+	}
+	fmt.Fprint(w, "\x1b\\")
+}
+
+// writeRuns writes a row of sixels, run length encoding repeated characters
+func writeRuns(w io.Writer, bits []byte) {
+	for x := 0; x < len(bits); {
+		run := 1
+		for x+run < len(bits) && bits[x+run] == bits[x] {
+			run++
+		}
+
+		char := bits[x] + 63
+		if run > 3 {
+			fmt.Fprintf(w, "!%d%c", run, char)
+		} else {
+			for i := 0; i < run; i++ {
+				fmt.Fprintf(w, "%c", char)
+			}
+		}
+		x += run
+	}
+}
+
+// quantize maps a channel onto the 6 levels of the color cube
+func quantize(v uint8) int {
+	return (int(v)*5 + 127) / 255
+}
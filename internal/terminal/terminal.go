@@ -0,0 +1,154 @@
+package terminal
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"strings"
+)
+
+// Mode is the way an image is drawn in the terminal
+type Mode int
+
+const (
+	TrueColor Mode = iota // 24 bit ANSI colors on half-block characters
+	Color256              // 256 ANSI colors on half-block characters
+	Sixel                 // Sixel graphics
+)
+
+// Modes maps the names accepted by ModeByName to their mode
+var Modes = map[string]Mode{
+	"truecolor": TrueColor,
+	"256":       Color256,
+	"sixel":     Sixel,
+}
+
+// ModeByName returns the mode with the given name, "auto" detects the mode from the environment
+func ModeByName(name string) (Mode, error) {
+	if name == "auto" {
+		return Detect(), nil
+	}
+	mode, ok := Modes[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown terminal preview mode %q", name)
+	}
+	return mode, nil
+}
+
+// Detect guesses the best mode supported by the terminal from the environment
+func Detect() Mode {
+	term := os.Getenv("TERM")
+	if strings.Contains(term, "sixel") || term == "mlterm" || term == "foot" || term == "contour" || os.Getenv("TERM_PROGRAM") == "WezTerm" {
+		return Sixel
+	}
+	switch os.Getenv("COLORTERM") {
+	case "truecolor", "24bit":
+		return TrueColor
+	}
+	return Color256
+}
+
+// A Preview draws images in the terminal, every draw replaces the previous one
+type Preview struct {
+	w     io.Writer
+	mode  Mode
+	lines int  // Number of lines of the last character based draw
+	drawn bool // Whether an image has been drawn
+}
+
+// New creates a preview writing to w
+func New(w io.Writer, mode Mode) *Preview {
+	return &Preview{
+		w:    w,
+		mode: mode,
+	}
+}
+
+// Draw draws the image, replacing the previous one
+// For the character modes, every character shows two vertically stacked pixels of the image.
+func (p *Preview) Draw(img image.Image) error {
+	w := bufio.NewWriter(p.w)
+	if p.mode == Sixel {
+		if p.drawn {
+			// Back to where the previous image started
+			fmt.Fprint(w, "\x1b8")
+		} else {
+			fmt.Fprint(w, "\x1b7")
+		}
+		writeSixel(w, img)
+		fmt.Fprint(w, "\n")
+	} else {
+		if p.lines > 0 {
+			fmt.Fprintf(w, "\x1b[%dA", p.lines)
+		}
+		p.lines = p.writeBlocks(w, img)
+	}
+	p.drawn = true
+	return w.Flush()
+}
+
+// writeBlocks writes the image as upper half-blocks, the foreground is the top pixel and the background the bottom one
+// It returns the number of lines written.
+func (p *Preview) writeBlocks(w io.Writer, img image.Image) int {
+	b := img.Bounds()
+	lines := 0
+	for y := b.Min.Y; y < b.Max.Y; y += 2 {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			top := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			bottom := top
+			if y+1 < b.Max.Y {
+				bottom = color.RGBAModel.Convert(img.At(x, y+1)).(color.RGBA)
+			}
+
+			if p.mode == TrueColor {
+				fmt.Fprintf(w, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm▀", top.R, top.G, top.B, bottom.R, bottom.G, bottom.B)
+			} else {
+				fmt.Fprintf(w, "\x1b[38;5;%dm\x1b[48;5;%dm▀", ansi256(top), ansi256(bottom))
+			}
+		}
+		fmt.Fprint(w, "\x1b[0m\n")
+		lines++
+	}
+	return lines
+}
+
+// ansi256 returns the closest color of the 6x6x6 color cube or the grayscale ramp of the 256 color palette
+func ansi256(c color.RGBA) int {
+	levels := [6]int{0, 95, 135, 175, 215, 255}
+	cubeIndex := func(v uint8) int {
+		if v < 48 {
+			return 0
+		}
+		if v < 115 {
+			return 1
+		}
+		return (int(v) - 35) / 40
+	}
+
+	r, g, b := cubeIndex(c.R), cubeIndex(c.G), cubeIndex(c.B)
+	cube := 16 + 36*r + 6*g + b
+	cubeDistance := distance(c, levels[r], levels[g], levels[b])
+
+	// Grayscale ramp from 8 to 238 in steps of 10
+	average := (int(c.R) + int(c.G) + int(c.B)) / 3
+	grayIndex := 23
+	if average < 238 {
+		grayIndex = (average - 3) / 10
+		if grayIndex < 0 {
+			grayIndex = 0
+		}
+	}
+	grayLevel := 8 + 10*grayIndex
+	if distance(c, grayLevel, grayLevel, grayLevel) < cubeDistance {
+		return 232 + grayIndex
+	}
+	return cube
+}
+
+func distance(c color.RGBA, r, g, b int) int {
+	dr, dg, db := int(c.R)-r, int(c.G)-g, int(c.B)-b
+	return dr*dr + dg*dg + db*db
+}
@@ -0,0 +1,63 @@
+package object
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/ray"
+	"github.com/thijsheijden/go-raytracer/vector"
+	"math"
+	"math/rand"
+)
+
+// A BSDF is a material which can evaluate its scattering function for any pair of directions, not just sample it.
+// This lets a renderer importance sample it, or weigh it against other sampling strategies.
+// Directions are normalised and point away from the hit point: wo towards where the light goes, which is back
+// along the incoming ray, and wi towards where it comes from.
+type BSDF interface {
+	Material
+	// Evaluate returns the value of the BSDF multiplied by the cosine of the angle between wi and the normal
+	Evaluate(hit *Hit, wo, wi vector.Vector) color.RGB
+	// Sample picks a direction wi for wo, returning it with Evaluate(wo, wi) divided by PDF(wo, wi)
+	// It returns false when the light is absorbed.
+	Sample(hit *Hit, wo vector.Vector, random *rand.Rand) (wi vector.Vector, weight color.RGB, ok bool)
+	// PDF returns the probability density per solid angle of Sample picking wi for wo
+	PDF(hit *Hit, wo, wi vector.Vector) float64
+}
+
+// An orthonormal basis around a normal, the normal becomes the z axis
+type frame struct {
+	tangent, bitangent, normal vector.Vector
+}
+
+// newFrame builds a basis around the normalised normal n, without branches that would cause seams
+// From "Building an Orthonormal Basis, Revisited" by Duff et al.
+func newFrame(n vector.Vector) frame {
+	sign := math.Copysign(1, n.Z)
+	a := -1 / (sign + n.Z)
+	b := n.X * n.Y * a
+	return frame{
+		tangent:   vector.New(1+sign*n.X*n.X*a, sign*b, -sign*n.X),
+		bitangent: vector.New(b, sign+n.Y*n.Y*a, -n.Y),
+		normal:    n,
+	}
+}
+
+// toLocal expresses a world space direction in the basis
+func (f frame) toLocal(v vector.Vector) vector.Vector {
+	return vector.New(v.Dot(f.tangent), v.Dot(f.bitangent), v.Dot(f.normal))
+}
+
+// toWorld expresses a direction in the basis in world space
+func (f frame) toWorld(v vector.Vector) vector.Vector {
+	return f.tangent.Scale(v.X).Add(f.bitangent.Scale(v.Y)).Add(f.normal.Scale(v.Z))
+}
+
+// sampleScatter implements Material.Scatter for a BSDF by sampling it
+func sampleScatter(m BSDF, r *ray.Ray, hit *Hit, attenuation *color.RGB, scattered *ray.Ray, random *rand.Rand) bool {
+	wi, weight, ok := m.Sample(hit, r.Direction().Normalise().Scale(-1), random)
+	if !ok {
+		return false
+	}
+	*scattered = ray.New(hit.Point, wi)
+	*attenuation = weight
+	return true
+}
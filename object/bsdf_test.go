@@ -0,0 +1,159 @@
+package object
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/vector"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+var bsdfs = []struct {
+	name     string
+	material Material
+	peaked   bool // Too close to a mirror to integrate by sampling the sphere uniformly
+}{
+	{"gold", Conductor(conductors["gold"].n, conductors["gold"].k, 0.3), false},
+	{"smooth copper", Conductor(conductors["copper"].n, conductors["copper"].k, 0.05), true},
+	{"rough aluminium", Conductor(conductors["aluminium"].n, conductors["aluminium"].k, 0.9), false},
+	{"frosted glass", RoughDielectric(1.5, 0.4), false},
+	{"rough glass", RoughDielectric(1.5, 0.9), false},
+	{"plastic", Principled(PrincipledParameters{BaseColor: Constant(color.New(0.8, 0.1, 0.1)), Roughness: Scalar(0.3)}), false},
+	{"brushed metal", Principled(PrincipledParameters{Metallic: Scalar(1), Roughness: Scalar(0.5)}), false},
+	{"lacquered cloth", Principled(PrincipledParameters{Sheen: Scalar(1), Clearcoat: Scalar(1), ClearcoatRoughness: Scalar(0.3)}), false},
+	{"tinted glass", Principled(PrincipledParameters{BaseColor: Constant(color.New(0.5, 0.9, 0.6)), Transmission: Scalar(1), Roughness: Scalar(0.4)}), false},
+	{"everything", Principled(PrincipledParameters{
+		Metallic: Scalar(0.3), Roughness: Scalar(0.6), SpecularTint: Scalar(0.5), Sheen: Scalar(0.5),
+		Clearcoat: Scalar(0.5), ClearcoatRoughness: Scalar(0.2), Transmission: Scalar(0.5),
+	}), false},
+}
+
+// testHits are hits from outside and inside a surface, with the normal facing the incoming direction
+var testHits = []Hit{
+	{Normal: vector.New(0, 0, 1), FrontFace: true},
+	{Normal: vector.New(0.36, 0.48, 0.8), FrontFace: false},
+}
+
+// TestSampleMatchesEvaluate checks that the weight of a sample is the BSDF value divided by its PDF
+func TestSampleMatchesEvaluate(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for _, b := range bsdfs {
+		bsdf := b.material.(BSDF)
+		for _, hit := range testHits {
+			for i := 0; i < 1000; i++ {
+				wo := randomHemisphere(hit.Normal, random)
+				wi, weight, ok := bsdf.Sample(&hit, wo, random)
+				if !ok {
+					continue
+				}
+				pdf := bsdf.PDF(&hit, wo, wi)
+				if pdf <= 0 {
+					t.Fatalf("%s: sampled direction %v for %v has PDF %v", b.name, wi, wo, pdf)
+				}
+				value := bsdf.Evaluate(&hit, wo, wi)
+				want := value.Scale(float32(1 / pdf))
+				if !closeColors(weight, want, 1e-3) {
+					t.Fatalf("%s: sample weight %v for %v to %v, Evaluate/PDF is %v", b.name, weight, wo, wi, want)
+				}
+			}
+		}
+	}
+}
+
+// TestPDFIntegral checks that the PDF integrates to the fraction of samples which are not absorbed
+// Rough microfacets absorb a lot, a sample reflected into a neighbouring microfacet ends the path.
+func TestPDFIntegral(t *testing.T) {
+	if testing.Short() {
+		t.Skip("integrating by uniform sampling takes a while")
+	}
+	random := rand.New(rand.NewSource(1))
+	for _, b := range bsdfs {
+		if b.peaked {
+			continue
+		}
+		bsdf := b.material.(BSDF)
+		for _, hit := range testHits {
+			wo := randomHemisphere(hit.Normal, random)
+			const n = 200000
+			var sum float64
+			var scattered int
+			for i := 0; i < n; i++ {
+				sum += bsdf.PDF(&hit, wo, randomSphere(random)) * 4 * math.Pi
+				if _, _, ok := bsdf.Sample(&hit, wo, random); ok {
+					scattered++
+				}
+			}
+			integral, want := sum/n, float64(scattered)/n
+			if math.Abs(integral-want) > 0.05 {
+				t.Errorf("%s: PDF integrates to %.3f for %v, %.3f of the samples scatter", b.name, integral, wo, want)
+			}
+		}
+	}
+}
+
+// TestEnergyConservation checks that white materials reflect no more light than they receive, in a white furnace
+func TestEnergyConservation(t *testing.T) {
+	white := Constant(color.New(1, 1, 1))
+	materials := []struct {
+		name     string
+		material Material
+	}{
+		{"diffuse", Principled(PrincipledParameters{BaseColor: white})},
+		{"specular", Principled(PrincipledParameters{BaseColor: white, Specular: Scalar(1), Roughness: Scalar(0.2)})},
+		{"metal", Principled(PrincipledParameters{BaseColor: white, Metallic: Scalar(1), Roughness: Scalar(0.7)})},
+		{"sheen", Principled(PrincipledParameters{BaseColor: white, Sheen: Scalar(1), SheenTint: Scalar(0)})},
+		{"clearcoat", Principled(PrincipledParameters{BaseColor: white, Clearcoat: Scalar(1), Specular: Scalar(1)})},
+		{"glass", Principled(PrincipledParameters{BaseColor: white, Transmission: Scalar(1), Roughness: Scalar(0.1)})},
+	}
+
+	// Seen from outside, light entering glass is concentrated, which is not a gain of energy
+	hit := testHits[0]
+	random := rand.New(rand.NewSource(1))
+	for _, m := range materials {
+		bsdf := m.material.(BSDF)
+		for _, cos := range []float64{1, 0.5, 0.1} {
+			wo := vector.New(math.Sqrt(1-cos*cos), 0, cos)
+			const n = 100000
+			var sum float64
+			for i := 0; i < n; i++ {
+				wi, weight, ok := bsdf.Sample(&hit, wo, random)
+				if !ok {
+					continue
+				}
+				if wi.Dot(hit.Normal) < 0 {
+					weight = weight.Scale(1.5 * 1.5)
+				}
+				sum += float64(weight.R+weight.G+weight.B) / 3
+			}
+			if albedo := sum / n; albedo > 1.02 {
+				t.Errorf("%s reflects %.3f of the light at cosine %v", m.name, albedo, cos)
+			}
+		}
+	}
+}
+
+func randomSphere(random *rand.Rand) vector.Vector {
+	for {
+		v := vector.RandomInUnitSphere(random)
+		if l := v.Length(); l > 1e-3 {
+			return v.Scale(1 / l)
+		}
+	}
+}
+
+// randomHemisphere returns a direction on the side of n, not too close to grazing
+func randomHemisphere(n vector.Vector, random *rand.Rand) vector.Vector {
+	for {
+		v := randomSphere(random)
+		if v.Dot(n) > 0.05 {
+			return v
+		}
+	}
+}
+
+func closeColors(a, b color.RGB, tolerance float64) bool {
+	close := func(x, y float32) bool {
+		return math.Abs(float64(x-y)) <= tolerance*math.Max(1, math.Abs(float64(y)))
+	}
+	return close(a.R, b.R) && close(a.G, b.G) && close(a.B, b.B)
+}
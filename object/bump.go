@@ -0,0 +1,123 @@
+package object
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/ray"
+	"github.com/thijsheijden/go-raytracer/vector"
+	"math/rand"
+)
+
+// Step in U and V over which bump maps take the slope of their height
+const bumpDelta = 0.0005
+
+// A material whose normals are bent before the base material scatters light
+type perturbed struct {
+	base   Material
+	bender bender
+}
+
+// A bender bends the outward normal at a hit
+type bender interface {
+	bend(hit *Hit, outward vector.Vector) vector.Vector
+}
+
+type normalMap struct {
+	normals  Texture
+	strength float64
+}
+
+type bumpMap struct {
+	height Texture
+	scale  float64
+}
+
+// NormalMap returns base with its normals taken from a tangent space normal map, like the usual blue-ish images
+// Red, green and blue map to the directions of U, V and the normal, 0.5 being no tilt. strength scales the tilt,
+// 1 uses the normals as they are. Normal maps are data, load images for them with ImageData.
+func NormalMap(base Material, normals Texture, strength float64) Material {
+	return perturbed{
+		base:   base,
+		bender: normalMap{normals: normals, strength: strength},
+	}
+}
+
+// BumpMap returns base with the surface made uneven by a height map, from the red channel
+// The surface moves outward by height times scale, in scene units. Only the normals change, not the outline.
+func BumpMap(base Material, height Texture, scale float64) Material {
+	return perturbed{
+		base:   base,
+		bender: bumpMap{height: height, scale: scale},
+	}
+}
+
+// bend returns the outward normal from the normal map
+func (m normalMap) bend(hit *Hit, outward vector.Vector) vector.Vector {
+	c := m.normals.Value(hit)
+	x := (2*float64(c.R) - 1) * m.strength
+	y := (2*float64(c.G) - 1) * m.strength
+	z := 2*float64(c.B) - 1
+
+	// An orthonormal frame around the normal, with the tangent following U
+	tangent := hit.Tangent.Sub(outward.Scale(outward.Dot(hit.Tangent))).Normalise()
+	bitangent := outward.Cross(tangent)
+	return tangent.Scale(x).Add(bitangent.Scale(y)).Add(outward.Scale(z)).Normalise()
+}
+
+// bend returns the outward normal of the surface moved by the height map
+func (m bumpMap) bend(hit *Hit, outward vector.Vector) vector.Vector {
+	height := float64(m.height.Value(hit).R) * m.scale
+
+	// Heights a small step along U and V, textures which use the position follow the surface too
+	shifted := *hit
+	shifted.U += bumpDelta
+	shifted.Point = hit.Point.Add(hit.Tangent.Scale(bumpDelta))
+	heightU := float64(m.height.Value(&shifted).R) * m.scale
+	shifted = *hit
+	shifted.V += bumpDelta
+	shifted.Point = hit.Point.Add(hit.Bitangent.Scale(bumpDelta))
+	heightV := float64(m.height.Value(&shifted).R) * m.scale
+
+	// The derivatives of the moved surface, the change of the normal itself is left out
+	tangent := hit.Tangent.Add(outward.Scale((heightU - height) / bumpDelta))
+	bitangent := hit.Bitangent.Add(outward.Scale((heightV - height) / bumpDelta))
+	n := tangent.Cross(bitangent).Normalise()
+	if n.Dot(outward) < 0 {
+		n = n.Scale(-1)
+	}
+	return n
+}
+
+// perturb returns a copy of hit with the bent normal
+// Where the bent normal faces away from the ray, the surface would be lit from behind, so the normal is kept.
+func (m perturbed) perturb(r *ray.Ray, hit *Hit) *Hit {
+	outward := hit.Normal
+	if !hit.FrontFace {
+		outward = outward.Scale(-1)
+	}
+	n := m.bender.bend(hit, outward)
+	if !hit.FrontFace {
+		n = n.Scale(-1)
+	}
+	if r.Direction().Dot(n) >= 0 {
+		return hit
+	}
+	bent := *hit
+	bent.Normal = n
+	return &bent
+}
+
+func (m perturbed) Scatter(r *ray.Ray, hit *Hit, attenuation *color.RGB, scattered *ray.Ray, rand *rand.Rand) bool {
+	return m.base.Scatter(r, m.perturb(r, hit), attenuation, scattered, rand)
+}
+
+func (m perturbed) ScatterSpectral(r *ray.Ray, hit *Hit, wavelength float64, attenuation *float64, scattered *ray.Ray, rand *rand.Rand) bool {
+	return ScatterSpectral(m.base, r, m.perturb(r, hit), wavelength, attenuation, scattered, rand)
+}
+
+func (m perturbed) Visible(r *ray.Ray, hit *Hit) bool {
+	return visible(m.base, r, hit)
+}
+
+func (m perturbed) Emitted(hit *Hit) color.RGB {
+	return emitted(m.base, hit)
+}
@@ -0,0 +1,167 @@
+package object
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/ray"
+	"math/rand"
+)
+
+// Maximum number of times light bounces between a coat and its base before it is absorbed
+const maxCoatBounces = 8
+
+// Two materials blended by a weight, each hit picks one of them at random
+type mixed struct {
+	a, b    Material
+	weight  Texture // Probability of b, from the red channel
+	nesting uint64  // See nesting
+}
+
+// Mix returns a material which blends a and b, weight is the share of b between 0 and 1 from the red channel
+// A texture as weight makes patches of either material, like dust or rust on metal. A nil weight blends them equally.
+func Mix(a, b Material, weight Texture) Material {
+	if weight == nil {
+		weight = Scalar(0.5)
+	}
+	m := mixed{
+		a:      a,
+		b:      b,
+		weight: weight,
+	}
+	m.nesting = nesting(a) + 1
+	if b := nesting(b) + 1; b > m.nesting {
+		m.nesting = b
+	}
+	return m
+}
+
+// pick returns the material the ray hits
+// It decides by a hash of the ray and the hit instead of a random number, so Visible and Scatter agree on it.
+func (m mixed) pick(r *ray.Ray, hit *Hit) Material {
+	if float64(m.weight.Value(hit).R) > hashFloat(r, hit, m.nesting) {
+		return m.b
+	}
+	return m.a
+}
+
+func (m mixed) Visible(r *ray.Ray, hit *Hit) bool {
+	return visible(m.pick(r, hit), r, hit)
+}
+
+func (m mixed) Scatter(r *ray.Ray, hit *Hit, attenuation *color.RGB, scattered *ray.Ray, rand *rand.Rand) bool {
+	return m.pick(r, hit).Scatter(r, hit, attenuation, scattered, rand)
+}
+
+func (m mixed) ScatterSpectral(r *ray.Ray, hit *Hit, wavelength float64, attenuation *float64, scattered *ray.Ray, rand *rand.Rand) bool {
+	return ScatterSpectral(m.pick(r, hit), r, hit, wavelength, attenuation, scattered, rand)
+}
+
+func (m mixed) Emitted(hit *Hit) color.RGB {
+	w := m.weight.Value(hit).R
+	return emitted(m.a, hit).Scale(1 - w).Add(emitted(m.b, hit).Scale(w))
+}
+
+// A base under a smooth clear coat
+type coated struct {
+	base Material
+	ior  float64
+	tint color.RGB
+}
+
+// Coated returns base under a smooth dielectric coat with refraction index ior, like varnish on wood or lacquer on paint
+// Light passing through the coat, on its way in and on its way out, is multiplied by tint, white for a clear coat.
+// Light is reflected off the coat and bounces between the coat and the base, the coat does not bend it.
+// Rays from inside the base, like from glass, do not see the coat. Spectral renders scatter off the base like RGB renders.
+func Coated(base Material, ior float64, tint color.RGB) Material {
+	return coated{
+		base: base,
+		ior:  ior,
+		tint: tint,
+	}
+}
+
+func (m coated) Scatter(r *ray.Ray, hit *Hit, attenuation *color.RGB, scattered *ray.Ray, rand *rand.Rand) bool {
+	if !hit.FrontFace {
+		return m.base.Scatter(r, hit, attenuation, scattered, rand)
+	}
+
+	// Reflection off the top of the coat
+	unitDirection := r.Direction().Normalise()
+	if fresnelDielectric(-unitDirection.Dot(hit.Normal), m.ior) > rand.Float64() {
+		*attenuation = color.New(1, 1, 1)
+		*scattered = ray.New(hit.Point, unitDirection.Reflect(hit.Normal))
+		return true
+	}
+
+	// Bounce between the base and the coat until the light gets out
+	throughput := m.tint
+	incoming := *r
+	for bounce := 0; bounce < maxCoatBounces; bounce++ {
+		var baseAttenuation color.RGB
+		if !m.base.Scatter(&incoming, hit, &baseAttenuation, scattered, rand) {
+			return false
+		}
+		throughput = throughput.Mul(baseAttenuation.R, baseAttenuation.G, baseAttenuation.B)
+
+		out := scattered.Direction().Normalise()
+		cos := out.Dot(hit.Normal)
+		if cos <= 0 {
+			// Transmitted into the base
+			*attenuation = throughput
+			return true
+		}
+		throughput = throughput.Mul(m.tint.R, m.tint.G, m.tint.B)
+		if fresnelDielectric(cos, m.ior) <= rand.Float64() {
+			*attenuation = throughput
+			return true
+		}
+
+		// Reflected back down by the underside of the coat
+		throughput = throughput.Mul(m.tint.R, m.tint.G, m.tint.B)
+		incoming = ray.New(hit.Point, out.Reflect(hit.Normal))
+	}
+	return false
+}
+
+func (m coated) Visible(r *ray.Ray, hit *Hit) bool {
+	return visible(m.base, r, hit)
+}
+
+func (m coated) Emitted(hit *Hit) color.RGB {
+	return emitted(m.base, hit)
+}
+
+// Different materials for the outside and the inside of a surface
+type twoSided struct {
+	front, back Material
+}
+
+// TwoSided returns a material which is front on the outside of a surface and back on the inside
+func TwoSided(front, back Material) Material {
+	return twoSided{
+		front: front,
+		back:  back,
+	}
+}
+
+func (m twoSided) side(hit *Hit) Material {
+	if hit.FrontFace {
+		return m.front
+	}
+	return m.back
+}
+
+func (m twoSided) Scatter(r *ray.Ray, hit *Hit, attenuation *color.RGB, scattered *ray.Ray, rand *rand.Rand) bool {
+	return m.side(hit).Scatter(r, hit, attenuation, scattered, rand)
+}
+
+func (m twoSided) ScatterSpectral(r *ray.Ray, hit *Hit, wavelength float64, attenuation *float64, scattered *ray.Ray, rand *rand.Rand) bool {
+	return ScatterSpectral(m.side(hit), r, hit, wavelength, attenuation, scattered, rand)
+}
+
+func (m twoSided) Visible(r *ray.Ray, hit *Hit) bool {
+	return visible(m.side(hit), r, hit)
+}
+
+func (m twoSided) Emitted(hit *Hit) color.RGB {
+	return emitted(m.side(hit), hit)
+}
@@ -0,0 +1,136 @@
+package object
+
+import (
+	"fmt"
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/ray"
+	"github.com/thijsheijden/go-raytracer/vector"
+	"math"
+	"math/rand"
+)
+
+// Complex refractive index n + ik of a metal, for red, green and blue light
+type complexIOR struct {
+	n, k color.RGB
+}
+
+// Measured refractive indices, sampled at the wavelengths of the sRGB primaries
+var conductors = map[string]complexIOR{
+	"gold":      {n: color.New(0.18299, 0.42108, 1.3734), k: color.New(3.4242, 2.3459, 1.7704)},
+	"copper":    {n: color.New(0.27105, 0.67693, 1.3164), k: color.New(3.6092, 2.6248, 2.2921)},
+	"aluminium": {n: color.New(1.3456, 0.96521, 0.61722), k: color.New(7.4746, 6.3995, 5.3031)},
+}
+
+// Conductors lists the metals accepted by ConductorByName
+var Conductors = []string{"gold", "copper", "aluminium"}
+
+// ConductorByName returns a rough metal with the measured refractive index of the named metal
+func ConductorByName(name string, roughness float64) (Material, error) {
+	n, k, err := ConductorIOR(name)
+	if err != nil {
+		return nil, err
+	}
+	return Conductor(n, k, roughness), nil
+}
+
+// ConductorIOR returns the measured complex refractive index n + ik of the named metal
+func ConductorIOR(name string) (n, k color.RGB, err error) {
+	ior, ok := conductors[name]
+	if !ok {
+		return n, k, fmt.Errorf("unknown conductor %q", name)
+	}
+	return ior.n, ior.k, nil
+}
+
+// A metal with GGX microfacets, reflecting according to the Fresnel equations for its complex refractive index
+type conductor struct {
+	ior          complexIOR
+	distribution ggx
+}
+
+// Conductor returns a rough metal with complex refractive index n + ik per color channel
+// A roughness of 0 is a mirror, 1 is very rough. The material implements BSDF.
+func Conductor(n, k color.RGB, roughness float64) Material {
+	return conductor{
+		ior:          complexIOR{n: n, k: k},
+		distribution: newGGX(roughness),
+	}
+}
+
+func (m conductor) Scatter(r *ray.Ray, hit *Hit, attenuation *color.RGB, scattered *ray.Ray, rand *rand.Rand) bool {
+	return sampleScatter(m, r, hit, attenuation, scattered, rand)
+}
+
+func (m conductor) Evaluate(hit *Hit, wo, wi vector.Vector) color.RGB {
+	f := newFrame(hit.Normal)
+	wo, wi = f.toLocal(wo), f.toLocal(wi)
+	if wo.Z <= 0 || wi.Z <= 0 {
+		return color.RGB{}
+	}
+
+	h := wo.Add(wi).Normalise()
+	scale := m.distribution.d(h) * m.distribution.g2(wo, wi) / (4 * wo.Z)
+	return m.fresnel(wo.Dot(h)).Scale(float32(scale))
+}
+
+func (m conductor) Sample(hit *Hit, wo vector.Vector, random *rand.Rand) (vector.Vector, color.RGB, bool) {
+	f := newFrame(hit.Normal)
+	wo = f.toLocal(wo)
+	if wo.Z <= 0 {
+		return vector.Vector{}, color.RGB{}, false
+	}
+
+	h := m.distribution.sampleVisible(wo, random)
+	wi := reflect(wo, h)
+	if wi.Z <= 0 {
+		// Reflected into the surface by a neighbouring microfacet
+		return vector.Vector{}, color.RGB{}, false
+	}
+
+	// With visible normal sampling most terms cancel out of the weight
+	scale := m.distribution.g2(wo, wi) / m.distribution.g1(wo)
+	return f.toWorld(wi), m.fresnel(wo.Dot(h)).Scale(float32(scale)), true
+}
+
+func (m conductor) PDF(hit *Hit, wo, wi vector.Vector) float64 {
+	f := newFrame(hit.Normal)
+	wo, wi = f.toLocal(wo), f.toLocal(wi)
+	if wo.Z <= 0 || wi.Z <= 0 {
+		return 0
+	}
+
+	h := wo.Add(wi).Normalise()
+	return m.distribution.visiblePDF(wo, h) / (4 * wo.Dot(h))
+}
+
+// fresnel returns the reflectance per color channel for light hitting the metal at an angle with cosine cos
+func (m conductor) fresnel(cos float64) color.RGB {
+	return color.New(
+		float32(fresnelConductor(cos, float64(m.ior.n.R), float64(m.ior.k.R))),
+		float32(fresnelConductor(cos, float64(m.ior.n.G), float64(m.ior.k.G))),
+		float32(fresnelConductor(cos, float64(m.ior.n.B), float64(m.ior.k.B))),
+	)
+}
+
+// fresnelConductor returns the exact reflectance of unpolarised light coming from air onto a metal with
+// refractive index n + ik
+func fresnelConductor(cos, n, k float64) float64 {
+	cos = math.Max(0, math.Min(cos, 1))
+	cos2 := cos * cos
+	sin2 := 1 - cos2
+	n2, k2 := n*n, k*k
+
+	t0 := n2 - k2 - sin2
+	a2b2 := math.Sqrt(t0*t0 + 4*n2*k2)
+	a := math.Sqrt(math.Max(0, (a2b2+t0)/2))
+
+	t1 := a2b2 + cos2
+	t2 := 2 * cos * a
+	rs := (t1 - t2) / (t1 + t2)
+
+	t3 := cos2*a2b2 + sin2*sin2
+	t4 := t2 * sin2
+	rp := rs * (t3 - t4) / (t3 + t4)
+
+	return (rs + rp) / 2
+}
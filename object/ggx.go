@@ -0,0 +1,94 @@
+package object
+
+import (
+	"github.com/thijsheijden/go-raytracer/vector"
+	"math"
+	"math/rand"
+)
+
+// Smallest alpha of a microfacet distribution, smoother surfaces make the distribution numerically unstable
+const minAlpha = 1e-3
+
+// The GGX or Trowbridge-Reitz distribution of microfacet normals, isotropic, in the local frame of the surface
+type ggx struct {
+	alpha float64
+}
+
+// newGGX returns the distribution for a perceptual roughness between 0 and 1, alpha is its square
+func newGGX(roughness float64) ggx {
+	roughness = math.Max(0, math.Min(roughness, 1))
+	return ggx{
+		alpha: math.Max(roughness*roughness, minAlpha),
+	}
+}
+
+// d returns the density of microfacets with normal h
+func (g ggx) d(h vector.Vector) float64 {
+	if h.Z <= 0 {
+		return 0
+	}
+	a2 := g.alpha * g.alpha
+	t := h.Z*h.Z*(a2-1) + 1
+	return a2 / (math.Pi * t * t)
+}
+
+// lambda is the auxiliary function of the Smith masking function for direction w
+func (g ggx) lambda(w vector.Vector) float64 {
+	cos2 := w.Z * w.Z
+	if cos2 == 0 {
+		return math.Inf(1)
+	}
+	tan2 := math.Max(0, 1-cos2) / cos2
+	return (math.Sqrt(1+g.alpha*g.alpha*tan2) - 1) / 2
+}
+
+// g1 returns the fraction of microfacets visible from direction w
+func (g ggx) g1(w vector.Vector) float64 {
+	return 1 / (1 + g.lambda(w))
+}
+
+// g2 returns the fraction of microfacets visible from both directions, with correlated heights
+func (g ggx) g2(wo, wi vector.Vector) float64 {
+	return 1 / (1 + g.lambda(wo) + g.lambda(wi))
+}
+
+// visiblePDF returns the density of the visible normal h as seen from direction w
+func (g ggx) visiblePDF(w, h vector.Vector) float64 {
+	if w.Z == 0 {
+		return 0
+	}
+	return g.g1(w) * math.Abs(w.Dot(h)) * g.d(h) / math.Abs(w.Z)
+}
+
+// sampleVisible samples a microfacet normal visible from direction w, with density visiblePDF
+// From "Sampling the GGX Distribution of Visible Normals" by Heitz.
+func (g ggx) sampleVisible(w vector.Vector, random *rand.Rand) vector.Vector {
+	// Stretch the view direction so the distribution becomes a hemisphere
+	if w.Z < 0 {
+		w = w.Scale(-1)
+	}
+	vh := vector.New(g.alpha*w.X, g.alpha*w.Y, w.Z).Normalise()
+
+	// Basis around the stretched view direction
+	t1 := vector.New(1, 0, 0)
+	if lengthSquared := vh.X*vh.X + vh.Y*vh.Y; lengthSquared > 0 {
+		t1 = vector.New(-vh.Y, vh.X, 0).Scale(1 / math.Sqrt(lengthSquared))
+	}
+	t2 := vh.Cross(t1)
+
+	// Sample a disk, warped towards the part of the hemisphere visible from vh
+	r := math.Sqrt(random.Float64())
+	phi := 2 * math.Pi * random.Float64()
+	p1, p2 := r*math.Cos(phi), r*math.Sin(phi)
+	s := (1 + vh.Z) / 2
+	p2 = (1-s)*math.Sqrt(1-p1*p1) + s*p2
+
+	// Project onto the hemisphere and unstretch
+	nh := t1.Scale(p1).Add(t2.Scale(p2)).Add(vh.Scale(math.Sqrt(math.Max(0, 1-p1*p1-p2*p2))))
+	return vector.New(g.alpha*nh.X, g.alpha*nh.Y, math.Max(1e-6, nh.Z)).Normalise()
+}
+
+// reflect mirrors w around the normal h, both pointing away from the surface
+func reflect(w, h vector.Vector) vector.Vector {
+	return h.Scale(2 * w.Dot(h)).Sub(w)
+}
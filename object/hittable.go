@@ -1,8 +1,8 @@
 package object
 
 import (
-	"raytracer/internal/ray"
-	"raytracer/internal/vector"
+	"github.com/thijsheijden/go-raytracer/ray"
+	"github.com/thijsheijden/go-raytracer/vector"
 )
 
 // A Hittable object is an object that can be hit
@@ -17,6 +17,12 @@ type Hit struct {
 	T         float64       // The distance at which the hit occurred
 	FrontFace bool          // Whether the normal faces outwards
 	Material  Material      // A pointer to the material that was hit
+	Object    Hittable      // The object that was hit, materials which trace rays inside it need it
+	U, V      float64       // Surface coordinates of the hit point, between 0 and 1, used by textures
+
+	// Derivatives of the hit point along U and V, tangent to the surface, used by normal and bump maps
+	// Their cross product points the same way as the outward normal.
+	Tangent, Bitangent vector.Vector
 }
 
 // SetFaceNormal sets the normal based on the dot product between the ray direction and the outward normal
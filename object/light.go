@@ -0,0 +1,41 @@
+package object
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/ray"
+	"github.com/thijsheijden/go-raytracer/spectrum"
+	"math/rand"
+)
+
+// An Emitter is a material which gives off light
+type Emitter interface {
+	Material
+	Emitted(hit *Hit) color.RGB
+}
+
+// Diffuse area light, it emits the same radiance in every direction and scatters nothing
+type light struct {
+	radiance color.RGB
+}
+
+// Light creates a material which emits radiance from both sides of its surface
+// The radiance can be brighter than white, a small light has to be bright to light a scene.
+func Light(radiance color.RGB) Material {
+	return light{
+		radiance: radiance,
+	}
+}
+
+// BlackbodyLight creates a light with the color of a black body at a temperature in Kelvin, see spectrum.Blackbody
+// strength is the luminance of the light.
+func BlackbodyLight(kelvin, strength float64) Material {
+	return Light(spectrum.Blackbody(kelvin).Scale(float32(strength)))
+}
+
+func (m light) Scatter(r *ray.Ray, hit *Hit, attenuation *color.RGB, scattered *ray.Ray, rand *rand.Rand) bool {
+	return false
+}
+
+func (m light) Emitted(hit *Hit) color.RGB {
+	return m.radiance
+}
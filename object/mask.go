@@ -0,0 +1,123 @@
+package object
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/ray"
+	"math"
+	"math/rand"
+)
+
+// A MaskedMaterial has holes in it, like the gaps between leaves drawn on a quad or around a decal
+// Scene.Hit skips the hits which are not visible and looks further along the ray. The combinators and normal and
+// bump maps are MaskedMaterials too, they ask the material they wrap, so a mask can be wrapped in them.
+type MaskedMaterial interface {
+	Material
+	Visible(r *ray.Ray, hit *Hit) bool
+}
+
+// visible returns whether the ray hits m, which is always true when m has no mask
+func visible(m Material, r *ray.Ray, hit *Hit) bool {
+	if masked, ok := m.(MaskedMaterial); ok {
+		return masked.Visible(r, hit)
+	}
+	return true
+}
+
+// A material which is only there where its alpha is high enough
+type masked struct {
+	base       Material
+	alpha      Texture
+	threshold  float64
+	stochastic bool
+	nesting    uint64 // See nesting
+}
+
+// Cutout returns base with holes where alpha, from the red channel, is below threshold
+func Cutout(base Material, alpha Texture, threshold float64) Material {
+	return masked{
+		base:      base,
+		alpha:     alpha,
+		threshold: threshold,
+		nesting:   nesting(base) + 1,
+	}
+}
+
+// PartiallyOpaque returns base which lets rays through with a probability of one minus alpha, from the red channel
+// An alpha of 0.3 gives a surface which covers 30% of what is behind it, like a faded decal or a thin curtain.
+func PartiallyOpaque(base Material, alpha Texture) Material {
+	return masked{
+		base:       base,
+		alpha:      alpha,
+		stochastic: true,
+		nesting:    nesting(base) + 1,
+	}
+}
+
+// Visible returns whether the ray hits the material or passes through a hole
+// Partially opaque surfaces decide with a hash of the ray and the hit point instead of a random number, so the same
+// ray always gives the same answer and Scene.Hit needs no random source.
+func (m masked) Visible(r *ray.Ray, hit *Hit) bool {
+	alpha := float64(m.alpha.Value(hit).R)
+	if !m.stochastic {
+		return alpha >= m.threshold
+	}
+	if alpha >= 1 {
+		return true
+	}
+	if alpha <= 0 {
+		return false
+	}
+	return hashFloat(r, hit, m.nesting) < alpha
+}
+
+func (m masked) Scatter(r *ray.Ray, hit *Hit, attenuation *color.RGB, scattered *ray.Ray, rand *rand.Rand) bool {
+	return m.base.Scatter(r, hit, attenuation, scattered, rand)
+}
+
+func (m masked) ScatterSpectral(r *ray.Ray, hit *Hit, wavelength float64, attenuation *float64, scattered *ray.Ray, rand *rand.Rand) bool {
+	return ScatterSpectral(m.base, r, hit, wavelength, attenuation, scattered, rand)
+}
+
+func (m masked) Emitted(hit *Hit) color.RGB {
+	return emitted(m.base, hit)
+}
+
+// nesting returns how deeply materials which choose by hashFloat are nested in m
+// Nested choices hash with different salts, so an inner one does not depend on the outer one.
+func nesting(m Material) uint64 {
+	switch m := m.(type) {
+	case masked:
+		return m.nesting
+	case mixed:
+		return m.nesting
+	case perturbed:
+		return nesting(m.base)
+	case coated:
+		return nesting(m.base)
+	case twoSided:
+		front, back := nesting(m.front), nesting(m.back)
+		if front > back {
+			return front
+		}
+		return back
+	}
+	return 0
+}
+
+// hashFloat returns a number between 0 and 1 which depends on the ray, the point it hit and salt
+// It multiplies in the bits of the coordinates a 64 bit word at a time, like FNV does with bytes, and finishes with
+// the SplitMix64 mixer.
+func hashFloat(r *ray.Ray, hit *Hit, salt uint64) float64 {
+	origin, direction := r.Origin(), r.Direction()
+	h := uint64(14695981039346656037) ^ salt
+	for _, f := range []float64{origin.X, origin.Y, origin.Z, direction.X, direction.Y, direction.Z, hit.Point.X, hit.Point.Y, hit.Point.Z} {
+		h ^= math.Float64bits(f)
+		h *= 1099511628211
+	}
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return float64(h>>11) / (1 << 53)
+}
@@ -0,0 +1,218 @@
+package object
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/ray"
+	"github.com/thijsheijden/go-raytracer/spectrum"
+	"github.com/thijsheijden/go-raytracer/vector"
+	"math"
+	"math/rand"
+)
+
+// Material describes a material
+type Material interface {
+	Scatter(r *ray.Ray, hit *Hit, attenuation *color.RGB, scattered *ray.Ray, rand *rand.Rand) bool
+}
+
+// A SpectralMaterial scatters light depending on its wavelength, like glass splitting white light into colors
+// Spectral renders use ScatterSpectral instead of Scatter, with the wavelength in nanometres and a single attenuation.
+type SpectralMaterial interface {
+	Material
+	ScatterSpectral(r *ray.Ray, hit *Hit, wavelength float64, attenuation *float64, scattered *ray.Ray, rand *rand.Rand) bool
+}
+
+// ScatterSpectral scatters light of a wavelength in nanometres off m
+// A material which is not a SpectralMaterial scatters like in RGB renders, its attenuation is turned into a spectrum.
+func ScatterSpectral(m Material, r *ray.Ray, hit *Hit, wavelength float64, attenuation *float64, scattered *ray.Ray, rand *rand.Rand) bool {
+	if spectral, ok := m.(SpectralMaterial); ok {
+		return spectral.ScatterSpectral(r, hit, wavelength, attenuation, scattered, rand)
+	}
+	var rgb color.RGB
+	ok := m.Scatter(r, hit, &rgb, scattered, rand)
+	*attenuation = spectrum.Reflectance(rgb, wavelength)
+	return ok
+}
+
+// emitted returns the light m emits, black when it is not an Emitter
+func emitted(m Material, hit *Hit) color.RGB {
+	if emitter, ok := m.(Emitter); ok {
+		return emitter.Emitted(hit)
+	}
+	return color.RGB{}
+}
+
+// Basic diffuse material
+type lambertian struct {
+	albedo color.RGB
+}
+
+// Lambertian returns a lambertian material
+func Lambertian(albedo color.RGB) Material {
+	return lambertian{
+		albedo: albedo,
+	}
+}
+
+func (m lambertian) Scatter(r *ray.Ray, hit *Hit, attenuation *color.RGB, scattered *ray.Ray, rand *rand.Rand) bool {
+	scatterDirection := hit.Normal.Add(vector.RandomInUnitSphere(rand))
+
+	// Catch near zero scatter direction
+	if scatterDirection.NearZero() {
+		scatterDirection = hit.Normal
+	}
+
+	scatteredRay := ray.New(hit.Point, scatterDirection)
+	*scattered = scatteredRay
+	*attenuation = m.albedo
+	return true
+}
+
+// Metal material
+type metal struct {
+	albedo color.RGB
+}
+
+// Metal returns a metal material
+func Metal(albedo color.RGB) Material {
+	return metal{
+		albedo: albedo,
+	}
+}
+
+func (m metal) Scatter(r *ray.Ray, hit *Hit, attenuation *color.RGB, scattered *ray.Ray, rand *rand.Rand) bool {
+	reflected := r.Direction().Normalise().Reflect(hit.Normal)
+	*scattered = ray.New(hit.Point, reflected)
+	*attenuation = m.albedo
+	return scattered.Direction().Dot(hit.Normal) > 0
+}
+
+type fuzzyMetal struct {
+	albedo    color.RGB
+	fuzziness float64
+}
+
+// FuzzyMetal returns a fuzzy metal material
+// It blurs the reflection without a physical basis, Conductor gives rough metals which behave like real ones.
+func FuzzyMetal(albedo color.RGB, fuzziness float64) Material {
+	return fuzzyMetal{
+		albedo:    albedo,
+		fuzziness: fuzziness,
+	}
+}
+
+func (m fuzzyMetal) Scatter(r *ray.Ray, hit *Hit, attenuation *color.RGB, scattered *ray.Ray, rand *rand.Rand) bool {
+	reflected := r.Direction().Normalise().Reflect(hit.Normal)
+	*scattered = ray.New(hit.Point, reflected.Add(vector.RandomInUnitSphere(rand).Scale(m.fuzziness)))
+	*attenuation = m.albedo
+	return scattered.Direction().Dot(hit.Normal) > 0
+}
+
+type dielectric struct {
+	refractionIndex float64
+	absorption      [3]float64   // Absorption coefficient per unit of distance for each color channel, sigma
+	dispersion      spectrum.IOR // Refraction index per wavelength in spectral renders, nil when it is constant
+}
+
+// Dielectric creates a new dieletric material with the given refraction index
+func Dielectric(refractionIndex float64) Material {
+	return dielectric{
+		refractionIndex: refractionIndex,
+	}
+}
+
+// AbsorbingDielectric creates a dielectric which absorbs light inside it, like colored glass, wine or deep water
+// Light which travelled distance through it is tinted to color, following the Beer-Lambert law: thicker parts get
+// darker and more saturated. Color channels are clamped between 0 and 1, a distance of 0 or less absorbs nothing.
+func AbsorbingDielectric(refractionIndex float64, color color.RGB, distance float64) Material {
+	m := dielectric{
+		refractionIndex: refractionIndex,
+	}
+	if distance <= 0 {
+		return m
+	}
+	for i, c := range []float32{color.R, color.G, color.B} {
+		m.absorption[i] = -math.Log(math.Max(0, math.Min(float64(c), 1))) / distance
+	}
+	return m
+}
+
+// DispersiveDielectric creates a dielectric whose refraction index depends on the wavelength, like a prism
+// Spectral renders split white light into colors with it, RGB renders use the index at the helium d line.
+func DispersiveDielectric(ior spectrum.IOR) Material {
+	return dielectric{
+		refractionIndex: ior.At(spectrum.HeliumD),
+		dispersion:      ior,
+	}
+}
+
+func (m dielectric) Scatter(r *ray.Ray, hit *Hit, attenuation *color.RGB, scattered *ray.Ray, rand *rand.Rand) bool {
+	*attenuation = m.transmittance(r, hit)
+	m.scatter(r, hit, m.refractionIndex, scattered, rand)
+	return true
+}
+
+func (m dielectric) ScatterSpectral(r *ray.Ray, hit *Hit, wavelength float64, attenuation *float64, scattered *ray.Ray, rand *rand.Rand) bool {
+	*attenuation = 1
+	if m.absorption != [3]float64{} {
+		*attenuation = spectrum.Reflectance(m.transmittance(r, hit), wavelength)
+	}
+	refractionIndex := m.refractionIndex
+	if m.dispersion != nil {
+		refractionIndex = m.dispersion.At(wavelength)
+	}
+	m.scatter(r, hit, refractionIndex, scattered, rand)
+	return true
+}
+
+// transmittance returns the fraction of light left after travelling through the inside up to hit
+func (m dielectric) transmittance(r *ray.Ray, hit *Hit) color.RGB {
+	// Hitting the inside of the surface, the ray travelled from where it entered or last reflected inside
+	if hit.FrontFace || m.absorption == [3]float64{} {
+		return color.New(1, 1, 1)
+	}
+	distance := hit.T * r.Direction().Length()
+	return color.New(
+		float32(math.Exp(-m.absorption[0]*distance)),
+		float32(math.Exp(-m.absorption[1]*distance)),
+		float32(math.Exp(-m.absorption[2]*distance)),
+	)
+}
+
+// scatter reflects or refracts the ray, following the Fresnel reflectance
+func (m dielectric) scatter(r *ray.Ray, hit *Hit, refractionIndex float64, scattered *ray.Ray, rand *rand.Rand) {
+	var refractionRatio float64
+	if hit.FrontFace {
+		refractionRatio = 1.0 / refractionIndex
+	} else {
+		refractionRatio = refractionIndex
+	}
+
+	unitDirection := r.Direction().Normalise()
+	cosTheta := min(unitDirection.Scale(-1).Dot(hit.Normal), 1.0)
+	sinTheta := math.Sqrt(1.0 - cosTheta*cosTheta)
+
+	cannotRefract := refractionRatio*sinTheta > 1.0
+	var direction vector.Vector
+
+	if cannotRefract || reflectance(cosTheta, refractionRatio) > rand.Float64() {
+		direction = unitDirection.Reflect(hit.Normal)
+	} else {
+		direction = unitDirection.Refract(hit.Normal, refractionRatio)
+	}
+
+	*scattered = ray.New(hit.Point, direction)
+}
+
+func reflectance(cos, refractionRatio float64) float64 {
+	// Use Schlick's approximation for reflectance.
+	r0 := (1 - refractionRatio) / (1 + refractionRatio)
+	r0 = r0 * r0
+	return r0 + (1-r0)*math.Pow(1-cos, 5)
+}
+
+func min(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,227 @@
+package object
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/ray"
+	"github.com/thijsheijden/go-raytracer/vector"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestAbsorbingDielectric(t *testing.T) {
+	m := AbsorbingDielectric(1.5, color.New(0.5, 0.25, 1), 2)
+	random := rand.New(rand.NewSource(1))
+
+	// The direction is not normalised, the distance travelled is 1.5 times its length of 2
+	r := ray.New(vector.New(0, 0, 0), vector.New(0, 0, -2))
+	inside := Hit{Point: vector.New(0, 0, -3), Normal: vector.New(0, 0, 1), T: 1.5, FrontFace: false}
+	var attenuation color.RGB
+	var scattered ray.Ray
+	m.Scatter(&r, &inside, &attenuation, &scattered, random)
+
+	// After 3 units the color of 2 units is applied one and a half times
+	want := color.New(float32(math.Pow(0.5, 1.5)), float32(math.Pow(0.25, 1.5)), 1)
+	if !closeColors(attenuation, want, 1e-6) {
+		t.Errorf("leaving the glass after a distance of 3 attenuates by %v, want %v", attenuation, want)
+	}
+
+	// Entering the glass absorbs nothing yet
+	outside := inside
+	outside.FrontFace = true
+	m.Scatter(&r, &outside, &attenuation, &scattered, random)
+	if attenuation != color.New(1, 1, 1) {
+		t.Errorf("entering the glass attenuates by %v", attenuation)
+	}
+
+	// Without a distance to tint over the glass is clear
+	for _, distance := range []float64{0, -1} {
+		AbsorbingDielectric(1.5, color.New(0.5, 0.25, 0), distance).Scatter(&r, &inside, &attenuation, &scattered, random)
+		if attenuation != color.New(1, 1, 1) {
+			t.Errorf("glass with an absorption distance of %v attenuates by %v", distance, attenuation)
+		}
+	}
+}
+
+func TestFilmReflectance(t *testing.T) {
+	// Without a film only the Fresnel reflectance of the base is left
+	if got, want := filmReflectance(1, 1, 1.33, 1.5, 0, 550), 0.04; math.Abs(got-want) > 1e-9 {
+		t.Errorf("glass without a film reflects %v, want %v", got, want)
+	}
+	if got := filmReflectance(0.6, 1, 1.33, 1, 0, 550); got > 1e-9 {
+		t.Errorf("a soap bubble without thickness reflects %v", got)
+	}
+	for _, cos := range []float64{1, 0.7, 0.2} {
+		got, want := filmReflectance(cos, 1, 1, complex(0.42108, 2.3459), 300, 550), fresnelConductor(cos, 0.42108, 2.3459)
+		if math.Abs(got-want) > 1e-6 {
+			t.Errorf("gold under a film of air at cos %v reflects %v, want %v", cos, got, want)
+		}
+	}
+
+	// A quarter wave coating of magnesium fluoride on glass is anti-reflective at its design wavelength
+	quarterWave := 550 / (4 * 1.38)
+	if got := filmReflectance(1, 1, 1.38, 1.5, quarterWave, 550); got > 0.015 {
+		t.Errorf("an anti-reflective coating reflects %v", got)
+	}
+
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		n2 := complex(1+2*random.Float64(), 3*random.Float64())
+		r := filmReflectance(random.Float64(), complex(1+random.Float64(), 0), complex(1+2*random.Float64(), 0), n2, 1000*random.Float64(), 400+300*random.Float64())
+		if r < 0 || r > 1 || math.IsNaN(r) {
+			t.Fatalf("reflectance %v is not between 0 and 1", r)
+		}
+	}
+
+	// A film without a thickness texture has the default thickness
+	r := ray.New(vector.New(0, 0, 1), vector.New(0.3, 0, -1))
+	hit := Hit{Point: vector.New(0, 0, 0), Normal: vector.New(0, 0, 1), T: 1, FrontFace: true}
+	var got, want color.RGB
+	var scattered ray.Ray
+	ThinFilmConductor(nil, 1.5, color.New(0.2, 0.9, 1.1), color.New(3.9, 2.5, 2.3)).Scatter(&r, &hit, &got, &scattered, random)
+	ThinFilmConductor(Scalar(defaultFilmThickness), 1.5, color.New(0.2, 0.9, 1.1), color.New(3.9, 2.5, 2.3)).Scatter(&r, &hit, &want, &scattered, random)
+	if got != want {
+		t.Errorf("a film without a thickness reflects %v, want %v", got, want)
+	}
+}
+
+func TestSubsurface(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for _, albedo := range []float32{0.2, 0.5, 0.8} {
+		// A sphere much larger than the mean free path reflects about its albedo under light from all directions
+		m := Subsurface(color.New(albedo, albedo, albedo), color.New(0.01, 0.02, 0.04), 1)
+		sphere := NewSphere(vector.New(0, 0, 0), 1, m)
+
+		const n = 4000
+		var sum [3]float64
+		for i := 0; i < n; i++ {
+			direction := randomSphere(random)
+			r := ray.New(direction.Scale(-3), direction.Add(vector.RandomInUnitSphere(random).Scale(0.2)))
+			var hit Hit
+			if !sphere.Intersect(&r, 0.001, math.Inf(1), &hit) {
+				continue
+			}
+			var attenuation color.RGB
+			var scattered ray.Ray
+			if !m.Scatter(&r, &hit, &attenuation, &scattered, random) {
+				continue
+			}
+			if l := scattered.Origin().Length(); math.Abs(l-1) > 1e-6 || scattered.Direction().Dot(scattered.Origin()) < 0 {
+				t.Fatalf("light leaves the sphere at distance %v from its center, in direction %v", l, scattered.Direction())
+			}
+			sum[0] += float64(attenuation.R)
+			sum[1] += float64(attenuation.G)
+			sum[2] += float64(attenuation.B)
+		}
+		for i := range sum {
+			if got := sum[i] / n; math.Abs(got-float64(albedo)) > 0.1 {
+				t.Errorf("albedo %v reflects %v in channel %d", albedo, got, i)
+			}
+		}
+	}
+}
+
+func TestCombinators(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	r := ray.New(vector.New(0, 0, 1), vector.New(0.3, 0, -1))
+	front := Hit{Point: vector.New(0, 0, 0), Normal: vector.New(0, 0, 1), T: 1, FrontFace: true}
+	back := front
+	back.FrontFace = false
+
+	red, blue := Lambertian(color.New(1, 0, 0)), Lambertian(color.New(0, 0, 1))
+	var attenuation color.RGB
+	var scattered ray.Ray
+	for _, test := range []struct {
+		name     string
+		material Material
+		hit      Hit
+		want     color.RGB
+	}{
+		{"mix without weight", Mix(red, blue, Scalar(0)), front, color.New(1, 0, 0)},
+		{"mix with full weight", Mix(red, blue, Scalar(1)), front, color.New(0, 0, 1)},
+		{"two sided front", TwoSided(red, blue), front, color.New(1, 0, 0)},
+		{"two sided back", TwoSided(red, blue), back, color.New(0, 0, 1)},
+	} {
+		for i := 0; i < 10; i++ {
+			if test.material.Scatter(&r, &test.hit, &attenuation, &scattered, random); attenuation != test.want {
+				t.Fatalf("%s attenuates by %v, want %v", test.name, attenuation, test.want)
+			}
+		}
+	}
+
+	// A clear coat on a white base loses only the light still bouncing between the two after the last bounce
+	coated := Coated(Lambertian(color.New(1, 1, 1)), 1.5, color.New(1, 1, 1))
+	const n = 20000
+	var sum float64
+	for i := 0; i < n; i++ {
+		if coated.Scatter(&r, &front, &attenuation, &scattered, random) {
+			if scattered.Direction().Dot(front.Normal) <= 0 {
+				t.Fatalf("light leaves a coated diffuse surface going down, in direction %v", scattered.Direction())
+			}
+			sum += float64(attenuation.G)
+		}
+	}
+	if albedo := sum / n; albedo < 0.97 || albedo > 1.001 {
+		t.Errorf("a clear coat over a white base reflects %v", albedo)
+	}
+
+	// Nested mixes choose independently of each other, a half of a half is a quarter
+	green := Lambertian(color.New(0, 1, 0))
+	nested := Mix(Mix(red, blue, Scalar(0.5)), green, Scalar(0.5))
+	blues := 0
+	for i := 0; i < n; i++ {
+		r := ray.New(vector.New(0, 0, 1), vector.RandomInUnitSphere(random))
+		if nested.Scatter(&r, &front, &attenuation, &scattered, random); attenuation.B == 1 {
+			blues++
+		}
+	}
+	if share := float64(blues) / n; math.Abs(share-0.25) > 0.02 {
+		t.Errorf("a mix in a mix picks its second material %v of the time, want 0.25", share)
+	}
+
+	// Without a weight both materials are picked as often
+	even := Mix(red, blue, nil)
+	blues = 0
+	for i := 0; i < n; i++ {
+		r := ray.New(vector.New(0, 0, 1), vector.RandomInUnitSphere(random))
+		if even.Scatter(&r, &front, &attenuation, &scattered, random); attenuation.B == 1 {
+			blues++
+		}
+	}
+	if share := float64(blues) / n; math.Abs(share-0.5) > 0.02 {
+		t.Errorf("a mix without a weight picks its second material %v of the time, want 0.5", share)
+	}
+}
+
+func TestFlatMaps(t *testing.T) {
+	sphere := NewSphere(vector.New(0, 0, -2), 1, nil)
+	r := ray.New(vector.New(0, 0, 0), vector.New(0.2, 0.3, -1))
+	var hit Hit
+	if !sphere.Intersect(&r, 0.001, math.Inf(1), &hit) {
+		t.Fatal("ray misses the sphere")
+	}
+
+	// Normal maps without tilt and bump maps without slope keep the normal
+	for name, m := range map[string]perturbed{
+		"normal map": NormalMap(nil, Constant(color.New(0.5, 0.5, 1)), 1).(perturbed),
+		"bump map":   BumpMap(nil, Scalar(0.3), 0.1).(perturbed),
+	} {
+		if n := m.perturb(&r, &hit).Normal; n.Sub(hit.Normal).Length() > 1e-6 {
+			t.Errorf("flat %s bends normal %v to %v", name, hit.Normal, n)
+		}
+	}
+
+	// A slope up along U tilts the normal back along the tangent
+	slope := BumpMap(nil, uTexture{}, 0.1).(perturbed)
+	n := slope.perturb(&r, &hit).Normal
+	if n.Dot(hit.Tangent) >= 0 {
+		t.Errorf("a slope up along U bends the normal to %v, not against tangent %v", n, hit.Tangent)
+	}
+}
+
+// uTexture is the U coordinate of the hit
+type uTexture struct{}
+
+func (uTexture) Value(hit *Hit) color.RGB {
+	return color.New(float32(hit.U), 0, 0)
+}
@@ -0,0 +1,110 @@
+package object
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/ray"
+	"github.com/thijsheijden/go-raytracer/vector"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Stored so the compiler can not optimise the benchmarked calls away
+var sinkBool bool
+
+// TestSphereTangents checks that moving along the tangent and bitangent of a hit moves along U and V
+func TestSphereTangents(t *testing.T) {
+	center := vector.New(1, 2, 3)
+	sphere := NewSphere(center, 2, nil)
+	random := rand.New(rand.NewSource(1))
+	const delta = 1e-6
+
+	// uv returns the surface coordinates of the point on the sphere in the direction of p from the center
+	uv := func(p vector.Vector) (float64, float64) {
+		r := ray.New(center, p.Sub(center))
+		var hit Hit
+		if !sphere.Intersect(&r, 0, math.Inf(1), &hit) {
+			t.Fatal("a ray from the center misses the sphere")
+		}
+		return hit.U, hit.V
+	}
+
+	for i := 0; i < 100; i++ {
+		direction := vector.RandomInUnitSphere(random).Normalise()
+		if math.Abs(direction.Y) > 0.99 || math.Abs(direction.Z) < 0.01 {
+			// Too close to the poles or to the seam where U wraps around
+			continue
+		}
+		var hit Hit
+		r := ray.New(center.Add(direction.Scale(10)), direction.Scale(-1))
+		if !sphere.Intersect(&r, 0.001, math.Inf(1), &hit) {
+			t.Fatal("a ray towards the center misses the sphere")
+		}
+
+		if hit.Tangent.Cross(hit.Bitangent).Dot(hit.Normal) <= 0 {
+			t.Errorf("tangent %v and bitangent %v do not follow the normal %v", hit.Tangent, hit.Bitangent, hit.Normal)
+		}
+		u, v := uv(hit.Point.Add(hit.Tangent.Scale(delta)))
+		if math.Abs(u-hit.U-delta) > delta/100 || math.Abs(v-hit.V) > delta/100 {
+			t.Errorf("moving along the tangent changes U and V by %v and %v, want %v and 0", u-hit.U, v-hit.V, delta)
+		}
+		u, v = uv(hit.Point.Add(hit.Bitangent.Scale(delta)))
+		if math.Abs(u-hit.U) > delta/100 || math.Abs(v-hit.V-delta) > delta/100 {
+			t.Errorf("moving along the bitangent changes U and V by %v and %v, want 0 and %v", u-hit.U, v-hit.V, delta)
+		}
+	}
+}
+
+func BenchmarkSphereIntersect(b *testing.B) {
+	sphere := NewSphere(vector.New(0, 0, -1), 0.5, Lambertian(color.New(0.5, 0.5, 0.5)))
+	rays := map[string]ray.Ray{
+		"hit":  ray.New(vector.New(0, 0, 0), vector.New(0.1, 0.1, -1)),
+		"miss": ray.New(vector.New(0, 0, 0), vector.New(1, 0.1, -1)),
+	}
+	for name, r := range rays {
+		r := r
+		b.Run(name, func(b *testing.B) {
+			var hit Hit
+			for i := 0; i < b.N; i++ {
+				sinkBool = sphere.Intersect(&r, 0.001, 1e9, &hit)
+			}
+		})
+	}
+}
+
+func BenchmarkScatter(b *testing.B) {
+	materials := []struct {
+		name     string
+		material Material
+	}{
+		{"lambertian", Lambertian(color.New(0.5, 0.5, 0.5))},
+		{"metal", Metal(color.New(0.8, 0.8, 0.8))},
+		{"fuzzyMetal", FuzzyMetal(color.New(0.8, 0.8, 0.8), 0.3)},
+		{"dielectric", Dielectric(1.5)},
+		{"conductor", Conductor(conductors["gold"].n, conductors["gold"].k, 0.3)},
+		{"roughDielectric", RoughDielectric(1.5, 0.3)},
+		{"principled", Principled(PrincipledParameters{Roughness: Scalar(0.3), Clearcoat: Scalar(0.5), Sheen: Scalar(0.5)})},
+		{"thinFilm", ThinFilm(Scalar(400), 1.33, 1)},
+		{"subsurface", Subsurface(color.New(0.8, 0.6, 0.5), color.New(0.3, 0.1, 0.05), 1.4)},
+	}
+
+	// A ray hitting the front of a sphere at an angle
+	r := ray.New(vector.New(0, 0, 0), vector.New(0.2, 0.1, -1))
+	sphere := NewSphere(vector.New(0, 0, -1), 0.5, nil)
+	var hit Hit
+	if !sphere.Intersect(&r, 0.001, 1e9, &hit) {
+		b.Fatal("benchmark ray misses the sphere")
+	}
+
+	for _, m := range materials {
+		material := m.material
+		b.Run(m.name, func(b *testing.B) {
+			random := rand.New(rand.NewSource(1))
+			var attenuation color.RGB
+			var scattered ray.Ray
+			for i := 0; i < b.N; i++ {
+				sinkBool = material.Scatter(&r, &hit, &attenuation, &scattered, random)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package object
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/ray"
+	"github.com/thijsheijden/go-raytracer/vector"
+	"math"
+	"math/rand"
+)
+
+// PrincipledParameters are the parameters of the principled material, all between 0 and 1 except IOR
+// Every parameter is a texture, a nil texture gives the default. Scalar parameters use the red channel of their
+// texture, see Scalar.
+type PrincipledParameters struct {
+	BaseColor          Texture // Color of the diffuse reflection, or the reflectance of a metal, default 0.8 gray
+	Metallic           Texture // Blends from a dielectric to a metal, default 0
+	Roughness          Texture // Roughness of the specular reflection and the transmission, default 0.5
+	Specular           Texture // Strength of the dielectric specular reflection, 0.5 reflects 4% head on, default 0.5
+	SpecularTint       Texture // Tints the dielectric specular reflection towards the base color, default 0
+	Sheen              Texture // Soft reflection at grazing angles, like that of cloth, default 0
+	SheenTint          Texture // Tints the sheen towards the base color, default 0.5
+	Clearcoat          Texture // Strength of a clear coat of lacquer on top of the material, default 0
+	ClearcoatRoughness Texture // Roughness of the clear coat, default 0.1
+	Transmission       Texture // Blends from an opaque to a transparent dielectric, default 0
+	IOR                float64 // Refraction index of the transmission, default 1.5
+}
+
+// A layered material in the style of the Disney principled BSDF
+// The base is a blend of diffuse, metal and glass, with a specular reflection on top, and a clear coat on top of it all.
+// Every layer only gets the light the layers on top let through, so the material never reflects more than it receives.
+type principled struct {
+	parameters PrincipledParameters
+}
+
+// Principled returns the principled material with the given parameters. The material implements BSDF.
+func Principled(parameters PrincipledParameters) Material {
+	if parameters.IOR == 0 {
+		parameters.IOR = 1.5
+	}
+	return principled{
+		parameters: parameters,
+	}
+}
+
+// The parameters at a hit point, with the weights of the layers derived from them
+type principledLobes struct {
+	baseColor    color.RGB
+	sheen        color.RGB // Color of the sheen, times its strength
+	specularF0   color.RGB // Specular reflectance head on
+	dielectricF0 float64   // Specular reflectance of the dielectric base head on
+	clearcoat    float64
+
+	specular, clearcoatLayer ggx
+	transmission             roughDielectric
+
+	// Fractions of the light going to each layer
+	clearcoatWeight, baseWeight                       float64
+	diffuseWeight, specularWeight, transmissionWeight float64
+
+	// Probabilities of sampling each lobe
+	pDiffuse, pSpecular, pTransmission, pClearcoat float64
+}
+
+func (m principled) lobes(hit *Hit, cosO float64) principledLobes {
+	p := m.parameters
+	scalar := func(t Texture, defaultValue float64) float64 {
+		if t == nil {
+			return defaultValue
+		}
+		return math.Max(0, math.Min(float64(t.Value(hit).R), 1))
+	}
+
+	baseColor := color.New(0.8, 0.8, 0.8)
+	if p.BaseColor != nil {
+		baseColor = p.BaseColor.Value(hit)
+	}
+	metallic := scalar(p.Metallic, 0)
+	roughness := scalar(p.Roughness, 0.5)
+	transmission := scalar(p.Transmission, 0)
+	clearcoat := scalar(p.Clearcoat, 0)
+
+	// The hue of the base color, used to tint the specular reflection and the sheen
+	tint := color.New(1, 1, 1)
+	if luminance := baseColor.Luminance(); luminance > 0 {
+		tint = baseColor.Scale(float32(1 / luminance))
+	}
+
+	l := principledLobes{
+		baseColor:      baseColor,
+		sheen:          mix(color.New(1, 1, 1), tint, scalar(p.SheenTint, 0.5)).Scale(float32(scalar(p.Sheen, 0))),
+		clearcoat:      clearcoat,
+		dielectricF0:   0.08 * scalar(p.Specular, 0.5),
+		specular:       newGGX(roughness),
+		clearcoatLayer: newGGX(scalar(p.ClearcoatRoughness, 0.1)),
+		transmission: roughDielectric{
+			refractionIndex: p.IOR,
+			distribution:    newGGX(roughness),
+		},
+		diffuseWeight:      (1 - metallic) * (1 - transmission),
+		specularWeight:     1 - (1-metallic)*transmission,
+		transmissionWeight: (1 - metallic) * transmission,
+	}
+	dielectricF0 := mix(color.New(1, 1, 1), tint, scalar(p.SpecularTint, 0)).Scale(float32(l.dielectricF0))
+	l.specularF0 = mix(dielectricF0, baseColor, metallic)
+
+	// The clear coat reflects some light before it reaches the base
+	l.clearcoatWeight = clearcoat * schlick(0.04, cosO)
+	l.baseWeight = 1 - l.clearcoatWeight
+
+	// Sample the lobes roughly by how much light they reflect
+	l.pDiffuse = l.baseWeight * l.diffuseWeight
+	l.pSpecular = l.baseWeight * l.specularWeight * math.Max(schlickColor(l.specularF0, cosO).Luminance(), 0.1)
+	l.pTransmission = l.baseWeight * l.transmissionWeight
+	l.pClearcoat = l.clearcoatWeight
+	if total := l.pDiffuse + l.pSpecular + l.pTransmission + l.pClearcoat; total > 0 {
+		l.pDiffuse /= total
+		l.pSpecular /= total
+		l.pTransmission /= total
+		l.pClearcoat /= total
+	}
+	return l
+}
+
+func (m principled) Scatter(r *ray.Ray, hit *Hit, attenuation *color.RGB, scattered *ray.Ray, rand *rand.Rand) bool {
+	return sampleScatter(m, r, hit, attenuation, scattered, rand)
+}
+
+func (m principled) Evaluate(hit *Hit, wo, wi vector.Vector) color.RGB {
+	f := newFrame(hit.Normal)
+	lo, li := f.toLocal(wo), f.toLocal(wi)
+	l := m.lobes(hit, lo.Z)
+
+	// Inside an object only the transmission leads anywhere
+	if !hit.FrontFace {
+		return l.transmission.Evaluate(hit, wo, wi).Mul(l.baseColor.R, l.baseColor.G, l.baseColor.B)
+	}
+
+	var value color.RGB
+	if lo.Z > 0 && li.Z > 0 {
+		h := lo.Add(li).Normalise()
+
+		// Diffuse gets the light which the dielectric specular reflection lets through
+		if l.diffuseWeight > 0 {
+			diffuse := l.baseColor.Scale(float32((1 - schlick(l.dielectricF0, lo.Z)) / math.Pi))
+			sheen := l.sheen.Scale(float32(math.Pow(1-li.Dot(h), 5)))
+			value = value.Add(diffuse.Add(sheen).Scale(float32(l.diffuseWeight * l.baseWeight * li.Z)))
+		}
+		if l.specularWeight > 0 {
+			scale := l.specular.d(h) * l.specular.g2(lo, li) / (4 * lo.Z) * l.specularWeight * l.baseWeight
+			value = value.Add(schlickColor(l.specularF0, li.Dot(h)).Scale(float32(scale)))
+		}
+		if l.clearcoat > 0 {
+			scale := l.clearcoat * schlick(0.04, li.Dot(h)) * l.clearcoatLayer.d(h) * l.clearcoatLayer.g2(lo, li) / (4 * lo.Z)
+			value = value.Add(color.New(1, 1, 1).Scale(float32(scale)))
+		}
+	}
+
+	if l.transmissionWeight > 0 {
+		transmission := l.transmission.Evaluate(hit, wo, wi).Scale(float32(l.transmissionWeight * l.baseWeight))
+		if li.Z < 0 {
+			transmission = transmission.Mul(l.baseColor.R, l.baseColor.G, l.baseColor.B)
+		}
+		value = value.Add(transmission)
+	}
+	return value
+}
+
+func (m principled) Sample(hit *Hit, wo vector.Vector, random *rand.Rand) (vector.Vector, color.RGB, bool) {
+	f := newFrame(hit.Normal)
+	lo := f.toLocal(wo)
+	l := m.lobes(hit, lo.Z)
+
+	if !hit.FrontFace {
+		wi, weight, ok := l.transmission.Sample(hit, wo, random)
+		return wi, weight.Mul(l.baseColor.R, l.baseColor.G, l.baseColor.B), ok
+	}
+	if lo.Z <= 0 {
+		return vector.Vector{}, color.RGB{}, false
+	}
+
+	// Pick a lobe to sample, the weight accounts for all lobes which could have given the direction
+	var wi vector.Vector
+	switch u := random.Float64(); {
+	case u < l.pDiffuse:
+		wi = f.toWorld(cosineHemisphere(random))
+	case u < l.pDiffuse+l.pSpecular:
+		wi = f.toWorld(reflect(lo, l.specular.sampleVisible(lo, random)))
+	case u < l.pDiffuse+l.pSpecular+l.pTransmission:
+		var ok bool
+		wi, _, ok = l.transmission.Sample(hit, wo, random)
+		if !ok {
+			return vector.Vector{}, color.RGB{}, false
+		}
+	default:
+		wi = f.toWorld(reflect(lo, l.clearcoatLayer.sampleVisible(lo, random)))
+	}
+
+	pdf := m.PDF(hit, wo, wi)
+	if pdf <= 0 {
+		return vector.Vector{}, color.RGB{}, false
+	}
+	return wi, m.Evaluate(hit, wo, wi).Scale(float32(1 / pdf)), true
+}
+
+func (m principled) PDF(hit *Hit, wo, wi vector.Vector) float64 {
+	f := newFrame(hit.Normal)
+	lo, li := f.toLocal(wo), f.toLocal(wi)
+	l := m.lobes(hit, lo.Z)
+
+	if !hit.FrontFace {
+		return l.transmission.PDF(hit, wo, wi)
+	}
+
+	var pdf float64
+	if lo.Z > 0 && li.Z > 0 {
+		h := lo.Add(li).Normalise()
+		pdf += l.pDiffuse * li.Z / math.Pi
+		pdf += l.pSpecular * l.specular.visiblePDF(lo, h) / (4 * lo.Dot(h))
+		pdf += l.pClearcoat * l.clearcoatLayer.visiblePDF(lo, h) / (4 * lo.Dot(h))
+	}
+	if l.pTransmission > 0 {
+		pdf += l.pTransmission * l.transmission.PDF(hit, wo, wi)
+	}
+	return pdf
+}
+
+// cosineHemisphere samples a direction around the Z axis with a density proportional to the cosine with it
+func cosineHemisphere(random *rand.Rand) vector.Vector {
+	r := math.Sqrt(random.Float64())
+	phi := 2 * math.Pi * random.Float64()
+	x, y := r*math.Cos(phi), r*math.Sin(phi)
+	return vector.New(x, y, math.Sqrt(math.Max(0, 1-x*x-y*y)))
+}
+
+// schlick returns Schlick's approximation of the Fresnel reflectance for reflectance f0 head on
+func schlick(f0, cos float64) float64 {
+	return f0 + (1-f0)*math.Pow(1-math.Max(0, math.Min(cos, 1)), 5)
+}
+
+func schlickColor(f0 color.RGB, cos float64) color.RGB {
+	return color.New(
+		float32(schlick(float64(f0.R), cos)),
+		float32(schlick(float64(f0.G), cos)),
+		float32(schlick(float64(f0.B), cos)),
+	)
+}
+
+// mix blends linearly from a to b
+func mix(a, b color.RGB, t float64) color.RGB {
+	return a.Scale(float32(1 - t)).Add(b.Scale(float32(t)))
+}
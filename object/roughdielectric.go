@@ -0,0 +1,180 @@
+package object
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/ray"
+	"github.com/thijsheijden/go-raytracer/vector"
+	"math"
+	"math/rand"
+)
+
+// Frosted glass, a dielectric with GGX microfacets which both reflect and refract
+// From "Microfacet Models for Refraction through Rough Surfaces" by Walter et al.
+type roughDielectric struct {
+	refractionIndex float64
+	distribution    ggx
+}
+
+// RoughDielectric returns a rough glass-like material with the given refraction index
+// A roughness of 0 is clear glass, 1 is very frosted. The material implements BSDF.
+func RoughDielectric(refractionIndex, roughness float64) Material {
+	return roughDielectric{
+		refractionIndex: refractionIndex,
+		distribution:    newGGX(roughness),
+	}
+}
+
+func (m roughDielectric) Scatter(r *ray.Ray, hit *Hit, attenuation *color.RGB, scattered *ray.Ray, rand *rand.Rand) bool {
+	return sampleScatter(m, r, hit, attenuation, scattered, rand)
+}
+
+// eta returns the ratio of the refraction index on the other side of the surface to the one on the side of the normal
+func (m roughDielectric) eta(hit *Hit) float64 {
+	if hit.FrontFace {
+		return m.refractionIndex
+	}
+	return 1 / m.refractionIndex
+}
+
+// halfVector returns the microfacet normal which scatters wo into wi, and the relative refraction index of the
+// transmission, which is 1 for a reflection. It returns false for directions no microfacet connects.
+func (m roughDielectric) halfVector(hit *Hit, wo, wi vector.Vector) (vector.Vector, float64, bool) {
+	if wo.Z == 0 || wi.Z == 0 {
+		return vector.Vector{}, 0, false
+	}
+	etaP := 1.0
+	if wo.Z*wi.Z < 0 {
+		etaP = m.eta(hit)
+		if wo.Z < 0 {
+			etaP = 1 / etaP
+		}
+	}
+
+	h := wi.Scale(etaP).Add(wo)
+	if h.Dot(h) == 0 {
+		return vector.Vector{}, 0, false
+	}
+	h = h.Normalise()
+	if h.Z < 0 {
+		h = h.Scale(-1)
+	}
+
+	// Both directions have to be on the front of the microfacet
+	if h.Dot(wi)*wi.Z < 0 || h.Dot(wo)*wo.Z < 0 {
+		return vector.Vector{}, 0, false
+	}
+	return h, etaP, true
+}
+
+func (m roughDielectric) Evaluate(hit *Hit, wo, wi vector.Vector) color.RGB {
+	f := newFrame(hit.Normal)
+	wo, wi = f.toLocal(wo), f.toLocal(wi)
+	h, etaP, ok := m.halfVector(hit, wo, wi)
+	if !ok {
+		return color.RGB{}
+	}
+
+	d, g := m.distribution.d(h), m.distribution.g2(wo, wi)
+	fresnel := fresnelDielectric(wo.Dot(h), m.eta(hit))
+	if wo.Z*wi.Z > 0 {
+		value := d * g * fresnel / (4 * math.Abs(wo.Z))
+		return color.New(1, 1, 1).Scale(float32(value))
+	}
+
+	// Radiance is compressed into a smaller solid angle when it enters a denser medium
+	denominator := wi.Dot(h) + wo.Dot(h)/etaP
+	denominator *= denominator
+	value := d * (1 - fresnel) * g * math.Abs(wi.Dot(h)*wo.Dot(h)/(wo.Z*denominator)) / (etaP * etaP)
+	return color.New(1, 1, 1).Scale(float32(value))
+}
+
+func (m roughDielectric) Sample(hit *Hit, wo vector.Vector, random *rand.Rand) (vector.Vector, color.RGB, bool) {
+	f := newFrame(hit.Normal)
+	wo = f.toLocal(wo)
+	if wo.Z == 0 {
+		return vector.Vector{}, color.RGB{}, false
+	}
+
+	h := m.distribution.sampleVisible(wo, random)
+	eta := m.eta(hit)
+	if wo.Z < 0 {
+		eta = 1 / eta
+	}
+
+	// Reflect or refract with the probability given by the Fresnel equations, which cancels them out of the weight,
+	// like visible normal sampling cancels most other terms
+	fresnel := fresnelDielectric(wo.Dot(h), eta)
+	var wi vector.Vector
+	if random.Float64() < fresnel {
+		wi = reflect(wo, h)
+		if wi.Z*wo.Z <= 0 {
+			return vector.Vector{}, color.RGB{}, false
+		}
+		eta = 1
+	} else {
+		var ok bool
+		wi, ok = refract(wo, h, eta)
+		if !ok || wi.Z*wo.Z >= 0 {
+			return vector.Vector{}, color.RGB{}, false
+		}
+	}
+	scale := m.distribution.g2(wo, wi) / m.distribution.g1(wo) / (eta * eta)
+	return f.toWorld(wi), color.New(1, 1, 1).Scale(float32(scale)), true
+}
+
+func (m roughDielectric) PDF(hit *Hit, wo, wi vector.Vector) float64 {
+	f := newFrame(hit.Normal)
+	wo, wi = f.toLocal(wo), f.toLocal(wi)
+	h, etaP, ok := m.halfVector(hit, wo, wi)
+	if !ok {
+		return 0
+	}
+
+	fresnel := fresnelDielectric(wo.Dot(h), m.eta(hit))
+	if wo.Z*wi.Z > 0 {
+		return m.distribution.visiblePDF(wo, h) / (4 * math.Abs(wo.Dot(h))) * fresnel
+	}
+	denominator := wi.Dot(h) + wo.Dot(h)/etaP
+	denominator *= denominator
+	return m.distribution.visiblePDF(wo, h) * math.Abs(wi.Dot(h)) / denominator * (1 - fresnel)
+}
+
+// fresnelDielectric returns the reflectance of unpolarised light hitting a dielectric surface at an angle with
+// cosine cos, where eta is the refraction index on the other side relative to the side the light comes from.
+// A negative cos means the light comes from the other side.
+func fresnelDielectric(cos, eta float64) float64 {
+	cos = math.Max(-1, math.Min(cos, 1))
+	if cos < 0 {
+		eta = 1 / eta
+		cos = -cos
+	}
+
+	sin2T := (1 - cos*cos) / (eta * eta)
+	if sin2T >= 1 {
+		// Total internal reflection
+		return 1
+	}
+	cosT := math.Sqrt(1 - sin2T)
+
+	parallel := (eta*cos - cosT) / (eta*cos + cosT)
+	perpendicular := (cos - eta*cosT) / (cos + eta*cosT)
+	return (parallel*parallel + perpendicular*perpendicular) / 2
+}
+
+// refract bends w, pointing away from the surface, through a surface with normal h and relative refraction index eta
+// The refracted direction points away from the other side of the surface. It returns false on total internal reflection.
+func refract(w, h vector.Vector, eta float64) (vector.Vector, bool) {
+	cos := w.Dot(h)
+	if cos < 0 {
+		eta = 1 / eta
+		cos = -cos
+		h = h.Scale(-1)
+	}
+
+	sin2T := math.Max(0, 1-cos*cos) / (eta * eta)
+	if sin2T >= 1 {
+		return vector.Vector{}, false
+	}
+	cosT := math.Sqrt(1 - sin2T)
+	return w.Scale(-1 / eta).Add(h.Scale(cos/eta - cosT)), true
+}
@@ -1,10 +1,10 @@
 package object
 
 import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/ray"
+	"github.com/thijsheijden/go-raytracer/vector"
 	"math"
-	"raytracer/internal/color"
-	"raytracer/internal/ray"
-	"raytracer/internal/vector"
 )
 
 // Sphere is a sphere, duh
@@ -58,6 +58,21 @@ func (s *Sphere) Intersect(r *ray.Ray, tMin, tMax float64, hit *Hit) bool {
 	outwardNormal := hit.Point.Sub(s.Center).Scale(1 / s.Radius)
 	hit.SetFaceNormal(r, &outwardNormal)
 	hit.Material = s.Material
+	hit.Object = s
+
+	// Latitude and longitude, U runs around the Y axis starting at -X, V from the bottom to the top
+	hit.U = (math.Atan2(-outwardNormal.Z, outwardNormal.X) + math.Pi) / (2 * math.Pi)
+	hit.V = math.Acos(math.Max(-1, math.Min(-outwardNormal.Y, 1))) / math.Pi
+
+	// U goes around the sphere once, V from pole to pole. At the poles any directions along the surface do.
+	x, y, z := outwardNormal.X, outwardNormal.Y, outwardNormal.Z
+	if sinTheta := math.Sqrt(x*x + z*z); sinTheta > 1e-9 {
+		hit.Tangent = vector.New(z, 0, -x).Scale(2 * math.Pi * s.Radius)
+		hit.Bitangent = vector.New(-y*x/sinTheta, sinTheta, -y*z/sinTheta).Scale(math.Pi * s.Radius)
+	} else {
+		hit.Tangent = vector.New(1, 0, 0)
+		hit.Bitangent = vector.New(0, 0, -y)
+	}
 
 	return true
 }
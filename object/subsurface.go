@@ -0,0 +1,164 @@
+package object
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/ray"
+	"github.com/thijsheijden/go-raytracer/vector"
+	"math"
+	"math/rand"
+)
+
+// Maximum number of scattering events in a random walk, light still inside after that is absorbed
+const maxWalkSteps = 256
+
+// Distance a walk keeps from the point it leaves, so it does not hit the surface it just entered through again
+// It is much smaller than the minimum distance of the renderer, light scattering just below the surface has to find it.
+const walkEpsilon = 1e-6
+
+// A translucent material, light enters through a smooth dielectric boundary, scatters around inside the object and
+// leaves it somewhere else, like in skin, wax, marble and milk
+type subsurface struct {
+	albedo           color.RGB
+	extinction       [3]float64 // Extinction coefficient per color channel, sigma t
+	scatteringAlbedo [3]float64 // Fraction of the extinction which is scattering and not absorption
+	ior              float64
+}
+
+// Subsurface returns a material which scatters light inside the object with a random walk
+// albedo is the color of the object seen from afar, meanFreePath the distance light of each color typically travels
+// inside it, in scene units. Larger distances make it more translucent. ior is the refraction index of the
+// boundary, about 1.4 for skin and 1.5 for marble.
+// The walk intersects only the object that was hit, Hit.Object, objects inside it are ignored. Without an object
+// the material is diffuse.
+func Subsurface(albedo, meanFreePath color.RGB, ior float64) Material {
+	m := subsurface{
+		albedo: albedo,
+		ior:    ior,
+	}
+
+	// Turn the albedo of the object into the albedo of a single scattering event, following "Practical and
+	// Controllable Subsurface Scattering for Production Path Tracing" by Chiang et al.
+	distances := []float32{meanFreePath.R, meanFreePath.G, meanFreePath.B}
+	for i, a := range []float32{albedo.R, albedo.G, albedo.B} {
+		a := math.Max(0, math.Min(float64(a), 1))
+		m.scatteringAlbedo[i] = 1 - math.Exp(a*(-5.09406+a*(2.61188-4.31805*a)))
+		s := 1.9 - a + 3.5*(a-0.8)*(a-0.8)
+		m.extinction[i] = 1 / math.Max(float64(distances[i])*s, 1e-6)
+	}
+	return m
+}
+
+func (m subsurface) Scatter(r *ray.Ray, hit *Hit, attenuation *color.RGB, scattered *ray.Ray, rand *rand.Rand) bool {
+	if hit.Object == nil {
+		return Lambertian(m.albedo).Scatter(r, hit, attenuation, scattered, rand)
+	}
+
+	unitDirection := r.Direction().Normalise()
+	direction, entering := m.cross(unitDirection, hit, rand)
+	if !entering {
+		// Reflected off the boundary, or a ray from inside, like from a camera placed in the object, left it
+		*attenuation = color.New(1, 1, 1)
+		*scattered = ray.New(hit.Point, direction)
+		return true
+	}
+
+	throughput, exit, exitDirection, ok := m.walk(hit.Object, hit.Point, direction, rand)
+	if !ok {
+		return false
+	}
+	*attenuation = color.New(float32(throughput[0]), float32(throughput[1]), float32(throughput[2]))
+	*scattered = ray.New(exit, exitDirection)
+	return true
+}
+
+// cross reflects a direction off the boundary or refracts it through, following the Fresnel reflectance
+// It returns the new direction and whether it goes into the object.
+func (m subsurface) cross(direction vector.Vector, hit *Hit, rand *rand.Rand) (vector.Vector, bool) {
+	refractionRatio := m.ior
+	if hit.FrontFace {
+		refractionRatio = 1 / m.ior
+	}
+	cosTheta := min(direction.Scale(-1).Dot(hit.Normal), 1.0)
+	sinTheta := math.Sqrt(1.0 - cosTheta*cosTheta)
+	if refractionRatio*sinTheta > 1.0 || reflectance(cosTheta, refractionRatio) > rand.Float64() {
+		return direction.Reflect(hit.Normal), !hit.FrontFace
+	}
+	return direction.Refract(hit.Normal, refractionRatio), hit.FrontFace
+}
+
+// walk follows light which entered the object at point in direction, until it leaves the object
+// It returns the throughput of the walk, and where and in which direction it left. Each step samples the distance
+// to the next scattering event for one color channel, picked in proportion to the throughput, and weighs all
+// channels by the average probability of that distance over the channels.
+func (m subsurface) walk(object Hittable, point, direction vector.Vector, rand *rand.Rand) ([3]float64, vector.Vector, vector.Vector, bool) {
+	throughput := [3]float64{1, 1, 1}
+	direction = direction.Normalise()
+	for step := 0; step < maxWalkSteps; step++ {
+		sum := throughput[0] + throughput[1] + throughput[2]
+		if sum <= 0 {
+			break
+		}
+		channel := 2
+		if u := rand.Float64() * sum; u < throughput[0] {
+			channel = 0
+		} else if u < throughput[0]+throughput[1] {
+			channel = 1
+		}
+		distance := -math.Log(1-rand.Float64()) / m.extinction[channel]
+
+		walkRay := ray.New(point, direction)
+		var boundary Hit
+		if object.Intersect(&walkRay, walkEpsilon, distance, &boundary) {
+			// Reached the boundary before scattering, the probability of getting this far is the transmittance
+			var transmittance [3]float64
+			var pdf float64
+			for i := range throughput {
+				transmittance[i] = math.Exp(-m.extinction[i] * boundary.T)
+				pdf += throughput[i] / sum * transmittance[i]
+			}
+			for i := range throughput {
+				throughput[i] *= transmittance[i] / pdf
+			}
+
+			point = boundary.Point
+			var entering bool
+			if direction, entering = m.cross(direction, &boundary, rand); !entering {
+				return throughput, point, direction, true
+			}
+			continue
+		}
+
+		// Scatter in a uniformly random direction
+		var weight [3]float64
+		var pdf float64
+		for i := range throughput {
+			transmittance := math.Exp(-m.extinction[i] * distance)
+			weight[i] = m.scatteringAlbedo[i] * m.extinction[i] * transmittance
+			pdf += throughput[i] / sum * m.extinction[i] * transmittance
+		}
+		for i := range throughput {
+			throughput[i] *= weight[i] / pdf
+		}
+		point = walkRay.At(distance)
+		direction = isotropic(rand)
+
+		// Russian roulette ends walks which carry little light
+		if q := math.Max(throughput[0], math.Max(throughput[1], throughput[2])); step > 3 && q < 1 {
+			if rand.Float64() > q {
+				break
+			}
+			for i := range throughput {
+				throughput[i] /= q
+			}
+		}
+	}
+	return throughput, point, direction, false
+}
+
+// isotropic returns a uniformly distributed direction
+func isotropic(random *rand.Rand) vector.Vector {
+	z := 1 - 2*random.Float64()
+	r := math.Sqrt(math.Max(0, 1-z*z))
+	phi := 2 * math.Pi * random.Float64()
+	return vector.New(r*math.Cos(phi), r*math.Sin(phi), z)
+}
@@ -0,0 +1,142 @@
+package object
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"image"
+	"math"
+)
+
+// A Texture gives a color for every point on a surface
+type Texture interface {
+	Value(hit *Hit) color.RGB
+}
+
+// The same color everywhere
+type constant struct {
+	color color.RGB
+}
+
+// Constant returns a texture of a single color
+func Constant(c color.RGB) Texture {
+	return constant{
+		color: c,
+	}
+}
+
+// Scalar returns a texture with the value v in every channel, for the scalar parameters of materials
+func Scalar(v float64) Texture {
+	return Constant(color.New(float32(v), float32(v), float32(v)))
+}
+
+func (t constant) Value(hit *Hit) color.RGB {
+	return t.color
+}
+
+// A 3D checkerboard, the same from every side of an object
+type checker struct {
+	even, odd Texture
+	scale     float64
+}
+
+// Checker returns a checkerboard alternating between two textures in cubes of size 1/scale
+func Checker(even, odd Texture, scale float64) Texture {
+	return checker{
+		even:  even,
+		odd:   odd,
+		scale: scale,
+	}
+}
+
+func (t checker) Value(hit *Hit) color.RGB {
+	p := hit.Point.Scale(t.scale)
+	if int(math.Floor(p.X)+math.Floor(p.Y)+math.Floor(p.Z))%2 == 0 {
+		return t.even.Value(hit)
+	}
+	return t.odd.Value(hit)
+}
+
+// An image wrapped around an object by its surface coordinates
+type imageTexture struct {
+	width, height int
+	pixels        []color.RGB // Linear colors, row by row from the top
+}
+
+// Image returns a texture of the image, mapped onto the U and V surface coordinates
+// The colors of the image are taken to be sRGB and made linear.
+func Image(img image.Image) Texture {
+	return newImageTexture(img, channels(linear))
+}
+
+// ImageData returns a texture of an image holding data like normals or heights, its values are used as they are
+func ImageData(img image.Image) Texture {
+	return newImageTexture(img, channels(fraction))
+}
+
+// ImageAlpha returns a gray texture of the alpha channel of an image, for the masks of cut-out materials
+func ImageAlpha(img image.Image) Texture {
+	return newImageTexture(img, func(_, _, _, a uint32) color.RGB {
+		v := fraction(a)
+		return color.New(v, v, v)
+	})
+}
+
+// newImageTexture turns every pixel into a color with convert, which gets the channels as returned by RGBA
+func newImageTexture(img image.Image, convert func(r, g, b, a uint32) color.RGB) Texture {
+	bounds := img.Bounds()
+	t := imageTexture{
+		width:  bounds.Dx(),
+		height: bounds.Dy(),
+		pixels: make([]color.RGB, bounds.Dx()*bounds.Dy()),
+	}
+	for y := 0; y < t.height; y++ {
+		for x := 0; x < t.width; x++ {
+			t.pixels[y*t.width+x] = convert(img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA())
+		}
+	}
+	return t
+}
+
+// channels returns a conversion for newImageTexture which converts the red, green and blue channels with convert
+func channels(convert func(c uint32) float32) func(r, g, b, a uint32) color.RGB {
+	return func(r, g, b, _ uint32) color.RGB {
+		return color.New(convert(r), convert(g), convert(b))
+	}
+}
+
+// Value interpolates between the four nearest pixels, the image repeats outside of U and V between 0 and 1
+// The interpolation keeps the texture continuous, which bump maps need for their slopes.
+func (t imageTexture) Value(hit *Hit) color.RGB {
+	x := hit.U*float64(t.width) - 0.5
+	y := (1-hit.V)*float64(t.height) - 0.5
+	x0, y0 := math.Floor(x), math.Floor(y)
+	tx, ty := float32(x-x0), float32(y-y0)
+
+	left, right := t.wrap(int(x0), t.width), t.wrap(int(x0)+1, t.width)
+	top, bottom := t.wrap(int(y0), t.height), t.wrap(int(y0)+1, t.height)
+	upper := t.pixels[top*t.width+left].Scale(1 - tx).Add(t.pixels[top*t.width+right].Scale(tx))
+	lower := t.pixels[bottom*t.width+left].Scale(1 - tx).Add(t.pixels[bottom*t.width+right].Scale(tx))
+	return upper.Scale(1 - ty).Add(lower.Scale(ty))
+}
+
+// wrap makes a pixel coordinate repeat within size
+func (t imageTexture) wrap(i, size int) int {
+	i %= size
+	if i < 0 {
+		i += size
+	}
+	return i
+}
+
+// linear undoes the sRGB transfer function of a 16 bit color channel
+func linear(c uint32) float32 {
+	v := float64(c) / 0xffff
+	if v <= 0.04045 {
+		return float32(v / 12.92)
+	}
+	return float32(math.Pow((v+0.055)/1.055, 2.4))
+}
+
+// fraction returns a 16 bit color channel as it is, between 0 and 1
+func fraction(c uint32) float32 {
+	return float32(c) / 0xffff
+}
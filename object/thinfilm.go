@@ -0,0 +1,182 @@
+package object
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/ray"
+	"github.com/thijsheijden/go-raytracer/spectrum"
+	"math"
+	"math/cmplx"
+	"math/rand"
+)
+
+// Number of wavelengths the reflectance of a thin film is evaluated at in RGB renders, enough for films up to about
+// a micrometre, thicker ones have color fringes too narrow to see
+const thinFilmWavelengths = 16
+
+// Wavelengths in nanometres of red, green and blue light, roughly the dominant wavelengths of the sRGB primaries
+var primaryWavelengths = [3]float64{630, 532, 465}
+
+// Thickness in nanometres of a film without a thickness texture
+const defaultFilmThickness = 400
+
+// A smooth surface coated with a film so thin that the light reflecting off its top and its bottom interferes,
+// which gives the color shifts of soap bubbles, oil on water and tempered steel
+type thinFilm struct {
+	thickness Texture // In nanometres, from the red channel
+	filmIOR   float64
+	base      complexIOR // Refractive index under the film, k is 0 for a dielectric
+	conductor bool       // The base is a metal, which reflects or absorbs all light
+}
+
+// ThinFilm returns a smooth dielectric with refraction index baseIOR, coated with a film with refraction index filmIOR
+// The thickness of the film in nanometres is taken from the red channel of a texture, colors show between about
+// 100 and 1000 nm, a nil texture gives 400 nm. With a baseIOR of 1 the film stands on its own like a soap bubble, light
+// passes straight through it.
+func ThinFilm(thickness Texture, filmIOR, baseIOR float64) Material {
+	return thinFilm{
+		thickness: filmThickness(thickness),
+		filmIOR:   filmIOR,
+		base:      complexIOR{n: color.New(float32(baseIOR), float32(baseIOR), float32(baseIOR))},
+	}
+}
+
+// ThinFilmConductor returns a smooth metal with complex refractive index n + ik coated with a thin film, like an oxide
+// layer. See ThinFilm for the thickness.
+func ThinFilmConductor(thickness Texture, filmIOR float64, n, k color.RGB) Material {
+	return thinFilm{
+		thickness: filmThickness(thickness),
+		filmIOR:   filmIOR,
+		base:      complexIOR{n: n, k: k},
+		conductor: true,
+	}
+}
+
+// filmThickness returns thickness, or the default thickness when it is nil
+func filmThickness(thickness Texture) Texture {
+	if thickness == nil {
+		return Scalar(defaultFilmThickness)
+	}
+	return thickness
+}
+
+func (m thinFilm) Scatter(r *ray.Ray, hit *Hit, attenuation *color.RGB, scattered *ray.Ray, rand *rand.Rand) bool {
+	unitDirection := r.Direction().Normalise()
+	cosTheta := min(unitDirection.Scale(-1).Dot(hit.Normal), 1.0)
+	thickness := float64(m.thickness.Value(hit).R)
+	reflectance := spectrum.Integrate(func(wavelength float64) float64 {
+		return m.reflectance(hit, cosTheta, thickness, wavelength)
+	}, thinFilmWavelengths)
+	reflectance = color.New(clamp01(reflectance.R), clamp01(reflectance.G), clamp01(reflectance.B))
+
+	if m.conductor {
+		*attenuation = reflectance
+		*scattered = ray.New(hit.Point, unitDirection.Reflect(hit.Normal))
+		return true
+	}
+
+	// Light which can not leave the base is reflected entirely, the film absorbs nothing
+	if m.cannotRefract(hit, cosTheta) {
+		*attenuation = color.New(1, 1, 1)
+		*scattered = ray.New(hit.Point, unitDirection.Reflect(hit.Normal))
+		return true
+	}
+
+	// Reflect or refract with the average reflectance, the attenuation makes up for the color
+	p := (reflectance.R + reflectance.G + reflectance.B) / 3
+	if rand.Float32() < p {
+		*attenuation = reflectance.Scale(1 / p)
+		*scattered = ray.New(hit.Point, unitDirection.Reflect(hit.Normal))
+	} else {
+		*attenuation = color.New(1-reflectance.R, 1-reflectance.G, 1-reflectance.B).Scale(1 / (1 - p))
+		*scattered = ray.New(hit.Point, unitDirection.Refract(hit.Normal, m.refractionRatio(hit)))
+	}
+	return true
+}
+
+func (m thinFilm) ScatterSpectral(r *ray.Ray, hit *Hit, wavelength float64, attenuation *float64, scattered *ray.Ray, rand *rand.Rand) bool {
+	unitDirection := r.Direction().Normalise()
+	cosTheta := min(unitDirection.Scale(-1).Dot(hit.Normal), 1.0)
+	reflectance := m.reflectance(hit, cosTheta, float64(m.thickness.Value(hit).R), wavelength)
+
+	*attenuation = 1
+	if m.conductor {
+		*attenuation = reflectance
+		*scattered = ray.New(hit.Point, unitDirection.Reflect(hit.Normal))
+	} else if m.cannotRefract(hit, cosTheta) || rand.Float64() < reflectance {
+		*scattered = ray.New(hit.Point, unitDirection.Reflect(hit.Normal))
+	} else {
+		*scattered = ray.New(hit.Point, unitDirection.Refract(hit.Normal, m.refractionRatio(hit)))
+	}
+	return true
+}
+
+// refractionRatio returns the ratio of the refraction indices on both sides, the film itself does not bend the light
+func (m thinFilm) refractionRatio(hit *Hit) float64 {
+	if hit.FrontFace {
+		return 1 / float64(m.base.n.G)
+	}
+	return float64(m.base.n.G)
+}
+
+func (m thinFilm) cannotRefract(hit *Hit, cosTheta float64) bool {
+	return m.refractionRatio(hit)*math.Sqrt(1-cosTheta*cosTheta) > 1
+}
+
+// reflectance returns the reflectance of the coated surface for light of a wavelength in nanometres
+func (m thinFilm) reflectance(hit *Hit, cosTheta, thickness, wavelength float64) float64 {
+	base := complex(interpolatePrimaries(m.base.n, wavelength), interpolatePrimaries(m.base.k, wavelength))
+	outside, inside := complex(1, 0), base
+	if !hit.FrontFace && !m.conductor {
+		outside, inside = base, complex(1, 0)
+	}
+	return filmReflectance(cosTheta, outside, complex(m.filmIOR, 0), inside, thickness, wavelength)
+}
+
+// filmReflectance returns the reflectance of a film with refraction index n1 and a thickness in nanometres, lying
+// between media with refraction indices n0, where the light comes from, and n2. The light is unpolarised and has a
+// wavelength in nanometres. The reflections off the top and the bottom of the film interfere, all internal reflections
+// are summed with the Airy formula. Complex indices absorb light.
+func filmReflectance(cos0 float64, n0, n1, n2 complex128, thickness, wavelength float64) float64 {
+	sin0 := complex(math.Sqrt(math.Max(0, 1-cos0*cos0)), 0)
+	c0 := complex(cos0, 0)
+	cos1 := cmplx.Sqrt(1 - (n0*sin0/n1)*(n0*sin0/n1))
+	cos2 := cmplx.Sqrt(1 - (n0*sin0/n2)*(n0*sin0/n2))
+
+	// Amplitudes of s and p polarised light reflected at the top and the bottom of the film
+	r01s := (n0*c0 - n1*cos1) / (n0*c0 + n1*cos1)
+	r12s := (n1*cos1 - n2*cos2) / (n1*cos1 + n2*cos2)
+	r01p := (n1*c0 - n0*cos1) / (n1*c0 + n0*cos1)
+	r12p := (n2*cos1 - n1*cos2) / (n2*cos1 + n1*cos2)
+
+	// Phase shift and attenuation of light travelling down and back up through the film
+	phase := cmplx.Exp(complex(0, 4*math.Pi*thickness/wavelength) * n1 * cos1)
+	rs := cmplx.Abs((r01s + r12s*phase) / (1 + r01s*r12s*phase))
+	rp := cmplx.Abs((r01p + r12p*phase) / (1 + r01p*r12p*phase))
+	return (rs*rs + rp*rp) / 2
+}
+
+// interpolatePrimaries returns the value of a property measured per color channel at a wavelength in nanometres
+func interpolatePrimaries(c color.RGB, wavelength float64) float64 {
+	red, green, blue := primaryWavelengths[0], primaryWavelengths[1], primaryWavelengths[2]
+	switch {
+	case wavelength <= blue:
+		return float64(c.B)
+	case wavelength <= green:
+		t := (wavelength - blue) / (green - blue)
+		return float64(c.B)*(1-t) + float64(c.G)*t
+	case wavelength <= red:
+		t := (wavelength - green) / (red - green)
+		return float64(c.G)*(1-t) + float64(c.R)*t
+	}
+	return float64(c.R)
+}
+
+func clamp01(v float32) float32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
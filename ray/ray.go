@@ -1,6 +1,6 @@
 package ray
 
-import "raytracer/internal/vector"
+import "github.com/thijsheijden/go-raytracer/vector"
 
 // Ray is a ray
 type Ray struct {
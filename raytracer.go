@@ -0,0 +1,136 @@
+// Package raytracer renders scenes of spheres with a multithreaded path tracer.
+//
+// A scene is built with the scene package, either in code or from a JSON description, and rendered with Render.
+// The resulting film holds linear radiance, which Develop turns into an image that can be saved with Encode or WriteImage.
+//
+//	s := scene.New(scene.NewCamera(position, lookAt, vup, 20, 16.0/9.0, 1), 16.0/9.0, 640)
+//	s.Spheres = append(s.Spheres, object.NewSphere(vector.New(0, 0, -1), 0.5, object.Lambertian(color.New(0.8, 0.3, 0.3))))
+//	f, err := raytracer.Render(ctx, &s, raytracer.DefaultOptions())
+//	...
+//	err = raytracer.WriteImage("out.png", raytracer.Develop(f, tonemap.Default()))
+//
+// The packages below this one give more control, like progressive and distributed rendering in render.
+package raytracer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/thijsheijden/go-raytracer/film"
+	"github.com/thijsheijden/go-raytracer/render"
+	"github.com/thijsheijden/go-raytracer/scene"
+	"github.com/thijsheijden/go-raytracer/tonemap"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// A FormatError reports an image format which is not supported
+type FormatError struct {
+	Format string
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("unknown image format %q, use jpeg or png", e.Format)
+}
+
+// An OutputError reports an image which could not be written
+type OutputError struct {
+	Path string
+	Err  error
+}
+
+func (e *OutputError) Error() string {
+	return fmt.Sprintf("writing image %s: %v", e.Path, e.Err)
+}
+
+func (e *OutputError) Unwrap() error {
+	return e.Err
+}
+
+// ErrInvalidOptions is returned by Render for options it can not render with
+var ErrInvalidOptions = render.ErrInvalidOptions
+
+// Film holds the rendered samples of an image, see film.Film
+type Film = film.Film
+
+// Options configure a render, see render.Options
+type Options = render.Options
+
+// DefaultOptions returns options for a good quality render using all CPUs
+func DefaultOptions() Options {
+	return Options{
+		SamplesPerPixel: 100,
+		MaxDepth:        50,
+		Threads:         runtime.NumCPU(),
+		Filter:          film.Box(0.5),
+		Seed:            1,
+	}
+}
+
+// Render renders the scene onto a film
+// When ctx is done before the render finished, the partially rendered film is returned together with the context's error.
+func Render(ctx context.Context, s *scene.Scene, options Options) (*Film, error) {
+	if s == nil {
+		return nil, errors.New("no scene to render")
+	}
+	if options.SamplesPerPixel <= 0 || options.MaxDepth <= 0 || options.Threads <= 0 {
+		return nil, fmt.Errorf("%w: samples per pixel, max depth and threads must be positive, got %d, %d and %d",
+			ErrInvalidOptions, options.SamplesPerPixel, options.MaxDepth, options.Threads)
+	}
+	if options.Filter == nil {
+		return nil, fmt.Errorf("%w: no reconstruction filter", ErrInvalidOptions)
+	}
+
+	f := render.New(s, options).Render(ctx)
+	return f, ctx.Err()
+}
+
+// Develop turns the linear radiance of a film into an sRGB image
+func Develop(f *Film, settings tonemap.Settings) *image.RGBA {
+	return settings.Image(f)
+}
+
+// Encode writes the image to w in the given format, either "jpeg" or "png"
+func Encode(w io.Writer, img image.Image, format string) error {
+	switch format {
+	case "jpeg", "jpg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 90})
+	case "png":
+		return png.Encode(w, img)
+	}
+	return &FormatError{Format: format}
+}
+
+// WriteImage saves the image to path, the format follows from the extension
+// The image is written to a temporary file first, so an interrupted write never leaves a broken file behind.
+func WriteImage(path string, img image.Image) error {
+	if err := writeImage(path, img); err != nil {
+		return &OutputError{Path: path, Err: err}
+	}
+	return nil
+}
+
+func writeImage(path string, img image.Image) error {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+
+	tmpPath := path + ".tmp"
+	output, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if err := Encode(output, img, format); err != nil {
+		output.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := output.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
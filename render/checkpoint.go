@@ -0,0 +1,113 @@
+package render
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"github.com/thijsheijden/go-raytracer/film"
+	"os"
+)
+
+// ErrCheckpointMismatch is returned when resuming from a checkpoint made for another scene or with other settings
+var ErrCheckpointMismatch = errors.New("checkpoint does not match the render")
+
+// A CheckpointError reports a checkpoint file which could not be written or read
+type CheckpointError struct {
+	Op   string // write or read
+	Path string
+	Err  error
+}
+
+func (e *CheckpointError) Error() string {
+	return fmt.Sprintf("%s checkpoint %s: %v", e.Op, e.Path, e.Err)
+}
+
+func (e *CheckpointError) Unwrap() error {
+	return e.Err
+}
+
+// A Checkpoint holds the state of a progressive render after a completed pass
+type Checkpoint struct {
+	SceneHash string     // Hash of the rendered scene
+	Settings  string     // Description of the render settings which affect the samples
+	Seed      int64      // Seed of the random generators
+	Passes    int        // Number of completed passes
+	Film      film.State // The samples of all completed passes
+}
+
+// Checkpoint returns a checkpoint of the progressive render after its last completed pass
+func (r *Renderer) Checkpoint() (*Checkpoint, error) {
+	if r.completed == nil {
+		return nil, errors.New("no progressive render to checkpoint")
+	}
+
+	return &Checkpoint{
+		SceneHash: r.Scene.Hash(),
+		Settings:  r.settings(),
+		Seed:      r.Options.Seed,
+		Passes:    r.passes,
+		Film:      r.completed.State(),
+	}, nil
+}
+
+// settings describes all options which affect the samples taken in a pass
+// The number of samples per pixel is left out, so a resumed render can take more passes
+func (r *Renderer) settings() string {
+	settings := fmt.Sprintf("maxDepth=%d filter=%#v", r.Options.MaxDepth, r.Options.Filter)
+	if r.Options.Spectral {
+		settings += " spectral"
+	}
+	return settings
+}
+
+// verify checks whether the checkpoint belongs to this renderer's scene and settings
+func (r *Renderer) verify(checkpoint *Checkpoint) error {
+	if checkpoint.SceneHash != r.Scene.Hash() {
+		return fmt.Errorf("%w: it was made for a different scene", ErrCheckpointMismatch)
+	}
+	if settings := r.settings(); checkpoint.Settings != settings {
+		return fmt.Errorf("%w: it was made with settings %q, now %q", ErrCheckpointMismatch, checkpoint.Settings, settings)
+	}
+	return nil
+}
+
+// WriteCheckpoint saves a checkpoint to path
+// It is written to a temporary file first, so an interrupted write never destroys the previous checkpoint
+func WriteCheckpoint(path string, checkpoint *Checkpoint) error {
+	if err := writeCheckpoint(path, checkpoint); err != nil {
+		return &CheckpointError{Op: "write", Path: path, Err: err}
+	}
+	return nil
+}
+
+func writeCheckpoint(path string, checkpoint *Checkpoint) error {
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	if err := gob.NewEncoder(file).Encode(checkpoint); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// ReadCheckpoint loads a checkpoint from path
+func ReadCheckpoint(path string) (*Checkpoint, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, &CheckpointError{Op: "read", Path: path, Err: err}
+	}
+	defer file.Close()
+
+	var checkpoint Checkpoint
+	if err := gob.NewDecoder(file).Decode(&checkpoint); err != nil {
+		return nil, &CheckpointError{Op: "read", Path: path, Err: err}
+	}
+	return &checkpoint, nil
+}
@@ -0,0 +1,34 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"github.com/thijsheijden/go-raytracer/film"
+	"github.com/thijsheijden/go-raytracer/scene"
+	"github.com/thijsheijden/go-raytracer/vector"
+	"reflect"
+	"testing"
+)
+
+// TestResumeMismatch checks that a checkpoint made with other settings is rejected without touching the options
+func TestResumeMismatch(t *testing.T) {
+	camera := scene.NewCamera(vector.New(0, 0, 0), vector.New(0, 0, -1), vector.New(0, 1, 0), 90, 1, 1)
+	s := scene.New(camera, 1, 4)
+	options := Options{SamplesPerPixel: 2, MaxDepth: 2, Threads: 1, Filter: film.Box(0.5), Seed: 1}
+
+	original := New(&s, options)
+	original.Progressive(context.Background(), nil)
+	checkpoint, err := original.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options.MaxDepth, options.Seed = 3, 2
+	r := New(&s, options)
+	if _, _, err := r.Resume(context.Background(), checkpoint, nil); !errors.Is(err, ErrCheckpointMismatch) {
+		t.Fatalf("got error %v, want ErrCheckpointMismatch", err)
+	}
+	if !reflect.DeepEqual(r.Options, options) {
+		t.Errorf("rejecting the checkpoint changed the options to %+v, want %+v", r.Options, options)
+	}
+}
@@ -0,0 +1,289 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/thijsheijden/go-raytracer/film"
+	"github.com/thijsheijden/go-raytracer/scene"
+	"image"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrWorkersLost is returned when all workers failed before every tile was rendered
+var ErrWorkersLost = errors.New("all workers lost")
+
+// ErrInvalidOptions is returned for options or coordinator settings which can not be rendered with
+var ErrInvalidOptions = errors.New("invalid render options")
+
+// Defaults of the coordinator settings which are left at 0
+const (
+	defaultTileHeight  = 16
+	defaultMaxFailures = 3
+)
+
+// Largest tile request a worker accepts, the scene with its embedded textures has to fit in it
+const maxTileRequestSize = 256 << 20
+
+// A TileRequest asks a worker to render one tile of a scene
+type TileRequest struct {
+	Scene           scene.Description
+	SamplesPerPixel int
+	MaxDepth        int
+	Filter          string
+	FilterRadius    float64
+	Seed            int64
+	Spectral        bool
+	Index           int             // Index of the tile, used to pick its random sequences
+	Tile            image.Rectangle // The pixels to render
+}
+
+// A Coordinator splits a render into tiles and distributes them over worker processes
+// Tiles of a worker which fails too often are handed to the remaining workers.
+type Coordinator struct {
+	Scene       scene.Description
+	Options     Options       // Threads is not used, every worker uses its own number of threads
+	Workers     []string      // Base URLs of the workers, like http://localhost:9001
+	TileHeight  int           // Number of rows in a tile, 16 when 0
+	TileTimeout time.Duration // Time a worker gets to render a tile, 0 means no limit
+	MaxFailures int           // Number of failures in a row after which a worker is considered lost, 3 when 0
+	Client      *http.Client  // http.DefaultClient when nil
+}
+
+// Render renders the scene on the workers and merges their tiles
+// When ctx is done the tiles rendered so far are returned, the other pixels stay black.
+// It fails when all workers are lost before every tile was rendered.
+func (c *Coordinator) Render(parent context.Context) (*film.Film, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	tileHeight, maxFailures := c.TileHeight, c.MaxFailures
+	if tileHeight == 0 {
+		tileHeight = defaultTileHeight
+	}
+	if maxFailures == 0 {
+		maxFailures = defaultMaxFailures
+	}
+
+	// Workers get the textures with the description, they might not have the files
+	description, err := c.Scene.Embed()
+	if err != nil {
+		return nil, err
+	}
+	s, err := description.Build()
+	if err != nil {
+		return nil, err
+	}
+	fullFilm := film.New(s.ImageWidth, s.ImageHeight, c.Options.Filter)
+
+	var tiles []image.Rectangle
+	for y := 0; y < s.ImageHeight; y += tileHeight {
+		tiles = append(tiles, image.Rect(0, y, s.ImageWidth, y+tileHeight).Intersect(fullFilm.Bounds))
+	}
+
+	// The queue can hold all tiles, so a failed tile can always be put back without blocking
+	queue := make(chan int, len(tiles))
+	for i := range tiles {
+		queue <- i
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	var mu sync.Mutex
+	remaining := len(tiles)
+
+	var wg sync.WaitGroup
+	for _, worker := range c.Workers {
+		wg.Add(1)
+		go func(worker string) {
+			defer wg.Done()
+			failures := 0
+			for {
+				var index int
+				select {
+				case <-ctx.Done():
+					return
+				case index = <-queue:
+				}
+
+				tileFilm := fullFilm.Tile(tiles[index])
+				err := c.renderTile(ctx, worker, description, index, tiles[index], tileFilm)
+				if err != nil {
+					queue <- index
+					if ctx.Err() != nil {
+						return
+					}
+
+					failures++
+					log.Printf("worker %s failed tile %d: %v", worker, index, err)
+					if failures >= maxFailures {
+						log.Printf("worker %s lost, its tiles go to the other workers", worker)
+						return
+					}
+
+					// Back off before trying again
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(time.Duration(failures) * time.Second):
+					}
+					continue
+				}
+				failures = 0
+
+				mu.Lock()
+				fullFilm.Merge(tileFilm)
+				if c.Options.OnTile != nil {
+					c.Options.OnTile(fullFilm)
+				}
+				remaining--
+				if remaining == 0 {
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	if remaining > 0 && parent.Err() == nil {
+		return nil, fmt.Errorf("%w with %d of %d tiles left", ErrWorkersLost, remaining, len(tiles))
+	}
+	return fullFilm, nil
+}
+
+// validate checks the settings and options a render can not do without
+func (c *Coordinator) validate() error {
+	if len(c.Workers) == 0 {
+		return fmt.Errorf("%w: no workers", ErrInvalidOptions)
+	}
+	if c.TileHeight < 0 || c.MaxFailures < 0 || c.TileTimeout < 0 {
+		return fmt.Errorf("%w: tile height, max failures and tile timeout can not be negative, got %d, %d and %v",
+			ErrInvalidOptions, c.TileHeight, c.MaxFailures, c.TileTimeout)
+	}
+	if c.Options.SamplesPerPixel <= 0 || c.Options.MaxDepth <= 0 {
+		return fmt.Errorf("%w: samples per pixel and max depth must be positive, got %d and %d",
+			ErrInvalidOptions, c.Options.SamplesPerPixel, c.Options.MaxDepth)
+	}
+	if c.Options.Filter == nil {
+		return fmt.Errorf("%w: no reconstruction filter", ErrInvalidOptions)
+	}
+	return nil
+}
+
+// renderTile has worker render a tile and restores the result into tileFilm
+func (c *Coordinator) renderTile(ctx context.Context, worker string, description scene.Description, index int, tile image.Rectangle, tileFilm *film.Film) error {
+	if c.TileTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.TileTimeout)
+		defer cancel()
+	}
+
+	body, err := json.Marshal(TileRequest{
+		Scene:           description,
+		SamplesPerPixel: c.Options.SamplesPerPixel,
+		MaxDepth:        c.Options.MaxDepth,
+		Filter:          c.Options.Filter.Name(),
+		FilterRadius:    c.Options.Filter.Radius(),
+		Seed:            c.Options.Seed,
+		Spectral:        c.Options.Spectral,
+		Index:           index,
+		Tile:            tile,
+	})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(worker, "/")+"/tile", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("%s: %s", response.Status, strings.TrimSpace(string(message)))
+	}
+
+	var state film.State
+	if err := gob.NewDecoder(response.Body).Decode(&state); err != nil {
+		return fmt.Errorf("decoding tile: %w", err)
+	}
+	return tileFilm.Restore(state)
+}
+
+// A Worker renders tiles for a coordinator, it serves POST requests on /tile
+type Worker struct {
+	Threads int // Number of threads to split a tile up
+}
+
+func (w *Worker) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/tile" {
+		http.NotFound(rw, req)
+		return
+	}
+	if req.Method != http.MethodPost {
+		http.Error(rw, "tiles must be requested with POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var tileRequest TileRequest
+	body := http.MaxBytesReader(rw, req.Body, maxTileRequestSize)
+	if err := json.NewDecoder(body).Decode(&tileRequest); err != nil {
+		http.Error(rw, fmt.Sprintf("decoding request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	s, err := tileRequest.Scene.Build()
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter, err := film.FilterByName(tileRequest.Filter, tileRequest.FilterRadius)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if tile := tileRequest.Tile; tile.Empty() || !tile.In(image.Rect(0, 0, s.ImageWidth, s.ImageHeight)) {
+		http.Error(rw, fmt.Sprintf("tile %v is not within the image", tile), http.StatusBadRequest)
+		return
+	}
+
+	renderer := New(&s, Options{
+		SamplesPerPixel: tileRequest.SamplesPerPixel,
+		MaxDepth:        tileRequest.MaxDepth,
+		Threads:         w.Threads,
+		Filter:          filter,
+		Seed:            tileRequest.Seed,
+		Spectral:        tileRequest.Spectral,
+	})
+	tileFilm := renderer.RenderTile(req.Context(), tileRequest.Tile, tileRequest.Index)
+	if req.Context().Err() != nil {
+		// The coordinator gave up on this tile
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/octet-stream")
+	if err := gob.NewEncoder(rw).Encode(tileFilm.State()); err != nil {
+		log.Printf("sending tile %d: %v", tileRequest.Index, err)
+	}
+}
@@ -0,0 +1,124 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/film"
+	"github.com/thijsheijden/go-raytracer/scene"
+	"github.com/thijsheijden/go-raytracer/vector"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestDistributedTexture renders a textured scene on workers which do not have the texture file
+func TestDistributedTexture(t *testing.T) {
+	dir := t.TempDir()
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	for i := range img.Pix {
+		img.Pix[i] = []uint8{255, 0, 0, 255}[i%4]
+	}
+	file, err := os.Create(filepath.Join(dir, "red.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(file, img); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	// A red sphere filling the view, the workers look for the texture relative to their working directory
+	description := scene.Description{
+		Camera:      scene.CameraDescription{Position: vector.New(0, 0, 0), LookAt: vector.New(0, 0, -1), VUp: vector.New(0, 1, 0), VerticalFOV: 20, FocalLength: 1},
+		AspectRatio: 1,
+		ImageWidth:  8,
+		Spheres: []scene.SphereDescription{{
+			Center: vector.New(0, 0, -3),
+			Radius: 2,
+			Material: scene.MaterialDescription{
+				Type:       "principled",
+				Principled: &scene.PrincipledDescription{BaseColor: &scene.TextureDescription{Type: "image", Path: "red.png"}},
+			},
+		}},
+		Dir: dir,
+	}
+
+	var workers []string
+	tilesMerged := 0
+	for i := 0; i < 2; i++ {
+		server := httptest.NewServer(&Worker{Threads: 1})
+		defer server.Close()
+		workers = append(workers, server.URL)
+	}
+	coordinator := Coordinator{
+		Scene: description,
+		Options: Options{
+			SamplesPerPixel: 4,
+			MaxDepth:        4,
+			Filter:          film.Box(0.5),
+			Seed:            1,
+			OnTile: func(*film.Film) {
+				tilesMerged++
+			},
+		},
+		Workers:     workers,
+		TileHeight:  2,
+		TileTimeout: time.Minute,
+		MaxFailures: 1,
+		Client:      http.DefaultClient,
+	}
+	f, err := coordinator.Render(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tilesMerged != 4 {
+		t.Errorf("OnTile was called %d times for 4 tiles", tilesMerged)
+	}
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if c := f.Pixel(x, y); !red(c) {
+				t.Fatalf("pixel (%d, %d) is %v, want the red of the texture", x, y, c)
+			}
+		}
+	}
+}
+
+func red(c color.RGB) bool {
+	return c.R > 0.05 && c.G < c.R/4 && c.B < c.R/4
+}
+
+// TestCoordinatorSettings checks that settings left at 0 get their defaults and invalid ones are rejected
+func TestCoordinatorSettings(t *testing.T) {
+	server := httptest.NewServer(&Worker{Threads: 1})
+	defer server.Close()
+	description := scene.Description{
+		Camera:      scene.CameraDescription{Position: vector.New(0, 0, 0), LookAt: vector.New(0, 0, -1), VUp: vector.New(0, 1, 0), VerticalFOV: 90, FocalLength: 1},
+		AspectRatio: 1,
+		ImageWidth:  4,
+	}
+	options := Options{SamplesPerPixel: 1, MaxDepth: 1, Filter: film.Box(0.5), Seed: 1}
+
+	coordinator := Coordinator{Scene: description, Options: options, Workers: []string{server.URL}}
+	if _, err := coordinator.Render(context.Background()); err != nil {
+		t.Errorf("render with default settings: %v", err)
+	}
+
+	for name, invalid := range map[string]Coordinator{
+		"no workers":           {Scene: description, Options: options},
+		"negative tile height": {Scene: description, Options: options, Workers: []string{server.URL}, TileHeight: -1},
+		"negative failures":    {Scene: description, Options: options, Workers: []string{server.URL}, MaxFailures: -1},
+		"no samples":           {Scene: description, Options: Options{MaxDepth: 1, Filter: film.Box(0.5)}, Workers: []string{server.URL}},
+		"no filter":            {Scene: description, Options: Options{SamplesPerPixel: 1, MaxDepth: 1}, Workers: []string{server.URL}},
+	} {
+		if _, err := invalid.Render(context.Background()); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("%s: got error %v, want ErrInvalidOptions", name, err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package render
+
+import (
+	"context"
+	"github.com/thijsheijden/go-raytracer/film"
+)
+
+// Progressive renders the image pass by pass, taking one sample per pixel across the whole frame in every pass
+// onPass is called with the accumulated film after each pass. The render stops after SamplesPerPixel passes
+// or when ctx is done, in which case the film holds all completed passes and the part of the interrupted pass
+// that was rendered. It returns the film and the number of completed passes.
+func (r *Renderer) Progressive(ctx context.Context, onPass func(pass int, f *film.Film)) (*film.Film, int) {
+	r.completed = r.newFilm()
+	r.passes = 0
+	return r.progressive(ctx, onPass)
+}
+
+// Resume continues a progressive render from a checkpoint, taking over its seed
+// It fails when the checkpoint was made for a different scene or with different settings.
+func (r *Renderer) Resume(ctx context.Context, checkpoint *Checkpoint, onPass func(pass int, f *film.Film)) (*film.Film, int, error) {
+	if err := r.verify(checkpoint); err != nil {
+		return nil, 0, err
+	}
+	completed := r.newFilm()
+	if err := completed.Restore(checkpoint.Film); err != nil {
+		return nil, 0, err
+	}
+
+	// Only a checkpoint which can be resumed changes the renderer
+	r.Options.Seed = checkpoint.Seed
+	r.completed = completed
+	r.passes = checkpoint.Passes
+
+	f, passes := r.progressive(ctx, onPass)
+	return f, passes, nil
+}
+
+func (r *Renderer) progressive(ctx context.Context, onPass func(pass int, f *film.Film)) (*film.Film, int) {
+	// Each pass is rendered onto its own film first, so the completed passes can be checkpointed at any time
+	passFilm := r.newFilm()
+	for r.passes < r.Options.SamplesPerPixel && ctx.Err() == nil {
+		passFilm.Reset()
+		r.renderPass(ctx, passFilm, passFilm.Bounds, 1, r.passes, nil)
+		if ctx.Err() != nil {
+			// The pass was interrupted before it covered the whole frame, return it together with the completed passes
+			partial := r.newFilm()
+			partial.Merge(r.completed)
+			partial.Merge(passFilm)
+			return partial, r.passes
+		}
+
+		r.completed.Merge(passFilm)
+		r.passes++
+
+		if onPass != nil {
+			onPass(r.passes, r.completed)
+		}
+	}
+	return r.completed, r.passes
+}
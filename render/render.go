@@ -0,0 +1,231 @@
+package render
+
+import (
+	"context"
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/film"
+	"github.com/thijsheijden/go-raytracer/object"
+	"github.com/thijsheijden/go-raytracer/ray"
+	"github.com/thijsheijden/go-raytracer/scene"
+	"github.com/thijsheijden/go-raytracer/spectrum"
+	"image"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+var infinity = math.Inf(1)
+
+// Width and height of the tiles a pass is split into
+const tileSize = 32
+
+// Options configure a render
+type Options struct {
+	SamplesPerPixel int         // Number of samples taken for each pixel
+	MaxDepth        int         // Maximum number of bounces of a path
+	Threads         int         // Number of threads to split the work up
+	Filter          film.Filter // Pixel reconstruction filter
+	Seed            int64       // Seed for the random generators, the same seed always gives the same image
+	Spectral        bool        // Trace every path at a single wavelength, which dispersive materials need
+
+	OnTile func(f *film.Film) // Called by Render and Coordinator.Render with the film after every completed tile, may be nil
+
+	// When not nil, the counts of the render are added to Stats after every tile. Every thread counts on its own,
+	// so collecting them costs little. Read it when the render is done, or between passes of a progressive render.
+	Stats *Stats
+}
+
+// A Renderer renders a scene onto a film
+type Renderer struct {
+	Scene   *scene.Scene
+	Options Options
+
+	// State of the progressive render, used for checkpoints
+	completed *film.Film // All completed passes
+	passes    int        // Number of completed passes
+
+	statsMutex sync.Mutex // Guards Options.Stats while threads add their counts
+}
+
+// New creates a new renderer for the scene
+func New(s *scene.Scene, options Options) *Renderer {
+	return &Renderer{
+		Scene:   s,
+		Options: options,
+	}
+}
+
+// Render renders the full image, the threads take tiles from a queue and take all samples for each tile at once
+// When ctx is done the threads stop after their current pixel, pixels that were not reached stay black
+func (r *Renderer) Render(ctx context.Context) *film.Film {
+	fullFilm := r.newFilm()
+	r.renderPass(ctx, fullFilm, fullFilm.Bounds, r.Options.SamplesPerPixel, 0, func() {
+		if r.Options.OnTile != nil {
+			r.Options.OnTile(fullFilm)
+		}
+	})
+	return fullFilm
+}
+
+// RenderTile renders the pixels within tile, split up over all threads
+// The returned film also holds the samples which landed just outside the tile, it can be merged into a full film.
+// pass distinguishes the random sequences of different tiles and passes.
+func (r *Renderer) RenderTile(ctx context.Context, tile image.Rectangle, pass int) *film.Film {
+	tileFilm := film.NewTile(r.Scene.ImageWidth, r.Scene.ImageHeight, tile, r.Options.Filter)
+	r.renderPass(ctx, tileFilm, tile, r.Options.SamplesPerPixel, pass, nil)
+	return tileFilm
+}
+
+// newFilm creates an empty film for the scene
+func (r *Renderer) newFilm() *film.Film {
+	return film.New(r.Scene.ImageWidth, r.Scene.ImageHeight, r.Options.Filter)
+}
+
+// mixSeed combines a seed with a pass and tile number, so neighbouring seeds don't give overlapping sequences
+func mixSeed(seed, pass, tile int64) int64 {
+	x := uint64(seed)
+	for _, v := range []int64{pass, tile} {
+		x ^= uint64(v) + 0x9e3779b97f4a7c15 + (x << 6) + (x >> 2)
+		x *= 0xbf58476d1ce4e5b9
+		x ^= x >> 31
+	}
+	return int64(x)
+}
+
+// renderPass takes nSamples samples for every pixel within region and adds them to f
+// The region is split into tiles, which the threads take from a queue. Every tile has its own random generator,
+// seeded from the render seed, the pass and the tile, so the image does not depend on the number of threads
+// and a resumed render does not need the generator state. onTile is called after each tile was added.
+// Samples taken before ctx is done are still added.
+func (r *Renderer) renderPass(ctx context.Context, f *film.Film, region image.Rectangle, nSamples, pass int, onTile func()) {
+	var tiles []image.Rectangle
+	for y := region.Min.Y; y < region.Max.Y; y += tileSize {
+		for x := region.Min.X; x < region.Max.X; x += tileSize {
+			tiles = append(tiles, image.Rect(x, y, x+tileSize, y+tileSize).Intersect(region))
+		}
+	}
+
+	queue := make(chan int, len(tiles))
+	for i := range tiles {
+		queue <- i
+	}
+	close(queue)
+
+	var wg sync.WaitGroup
+	tileChan := make(chan *film.Film, r.Options.Threads)
+	for thread := 0; thread < r.Options.Threads; thread++ {
+		// Join waitgroup
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			// Count in a thread local Stats, which is added to the total after each tile
+			var stats *Stats
+			if r.Options.Stats != nil {
+				stats = &Stats{}
+			}
+
+			for index := range queue {
+				if ctx.Err() != nil {
+					return
+				}
+
+				// Create film for this tile, samples near the edges also land on neighbouring tiles
+				tile := f.Tile(tiles[index])
+				random := rand.New(rand.NewSource(mixSeed(r.Options.Seed, int64(pass), int64(index))))
+				r.renderRows(ctx, tile, tiles[index], nSamples, random, stats)
+				if stats != nil {
+					r.statsMutex.Lock()
+					r.Options.Stats.Add(stats)
+					r.statsMutex.Unlock()
+					stats.reset()
+				}
+				tileChan <- tile
+			}
+		}()
+	}
+
+	// Close the channel once all threads are done
+	go func() {
+		wg.Wait()
+		close(tileChan)
+	}()
+
+	// Merge the tiles into the film as they come in
+	for tile := range tileChan {
+		f.Merge(tile)
+		if onTile != nil {
+			onTile()
+		}
+	}
+}
+
+// renderRows takes nSamples samples for every pixel in rows, y runs from the top of the image down
+// It returns early when ctx is done. The rays and paths are counted in stats, which may be nil.
+func (r *Renderer) renderRows(ctx context.Context, tile *film.Film, rows image.Rectangle, nSamples int, random *rand.Rand, stats *Stats) {
+	s := r.Scene
+	done := ctx.Done()
+	balance := s.Camera.WhiteBalanceGain()
+	for y := rows.Min.Y; y < rows.Max.Y; y++ {
+		for x := rows.Min.X; x < rows.Max.X; x++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			// Anti-aliasing, every sample is splatted onto the film with the filter weight
+			for i := 0; i < nSamples; i++ {
+				sx := float64(x) + random.Float64()
+				sy := float64(y) + random.Float64()
+				cameraRay := s.CameraRay(sx/s.FloatImageWidth, 1-sy/s.FloatImageHeight)
+				var c color.RGB
+				if r.Options.Spectral {
+					wavelength := spectrum.SampleWavelength(random.Float64())
+					c = spectrum.ToRGB(wavelength, r.spectralRay(cameraRay, r.Options.MaxDepth, wavelength, random, stats))
+				} else {
+					c = r.colorRay(cameraRay, r.Options.MaxDepth, random, stats)
+				}
+				tile.AddSample(sx, sy, c.Mul(balance.R, balance.G, balance.B))
+			}
+		}
+	}
+}
+
+// colorRay follows a ray through the scene, returning the color it carries back
+func (r *Renderer) colorRay(cameraRay ray.Ray, depth int, random *rand.Rand, stats *Stats) color.RGB {
+	// Reached max recursion depth
+	if depth <= 0 {
+		stats.endPath(r.Options.MaxDepth, depthLimited)
+		return color.New(0, 0, 0)
+	}
+	var hit object.Hit
+	var tests int64
+	hitAnything := r.Scene.CountedHit(&cameraRay, 0.001, infinity, &hit, &tests)
+	stats.traceRay(depth == r.Options.MaxDepth, tests)
+
+	if hitAnything {
+		var scattered ray.Ray
+		var attenuation color.RGB
+
+		var emitted color.RGB
+		if emitter, ok := hit.Material.(object.Emitter); ok {
+			emitted = emitter.Emitted(&hit)
+		}
+		if hit.Material.Scatter(&cameraRay, &hit, &attenuation, &scattered, random) {
+			return emitted.Add(r.colorRay(scattered, depth-1, random, stats).Mul(attenuation.R, attenuation.G, attenuation.B))
+		}
+		stats.endPath(r.Options.MaxDepth-depth+1, absorbed)
+		return emitted
+	}
+	stats.endPath(r.Options.MaxDepth-depth+1, escaped)
+	return r.sky(cameraRay)
+}
+
+// sky returns the color of the sky seen along a ray, a white to blue gradient based on y coord tinted by the scene
+func (r *Renderer) sky(cameraRay ray.Ray) color.RGB {
+	t := float32(0.5 * (cameraRay.Direction().Normalise().Y + 1.0))
+	tint := r.Scene.SkyTint
+	return color.New(1, 1, 1).Mul(1-t, 1-t, 1-t).Add(color.New(0.5, 0.7, 1).Mul(t, t, t)).Mul(tint.R, tint.G, tint.B)
+}
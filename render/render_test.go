@@ -0,0 +1,139 @@
+package render
+
+import (
+	"context"
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/film"
+	"github.com/thijsheijden/go-raytracer/object"
+	"github.com/thijsheijden/go-raytracer/scene"
+	"github.com/thijsheijden/go-raytracer/vector"
+	"math"
+	"math/rand"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestStats checks that the counts of a render are consistent with each other
+func TestStats(t *testing.T) {
+	camera := scene.NewCamera(vector.New(13, 2, 3), vector.New(0, 0, 0), vector.New(0, 1, 0), 20, 16.0/9.0, 1)
+	s := scene.New(camera, 16.0/9.0, 32)
+	s.LotsOfSpheres(rand.New(rand.NewSource(1)))
+
+	var stats Stats
+	New(&s, Options{
+		SamplesPerPixel: 2,
+		MaxDepth:        10,
+		Threads:         2,
+		Filter:          film.Box(0.5),
+		Seed:            1,
+		Stats:           &stats,
+	}).Render(context.Background())
+
+	if want := int64(s.ImageWidth * s.ImageHeight * 2); stats.CameraRays != want || stats.Paths() != want {
+		t.Errorf("got %d camera rays and %d paths, want %d of both", stats.CameraRays, stats.Paths(), want)
+	}
+	if want := stats.Rays() * int64(len(s.Spheres)); stats.IntersectionTests != want {
+		t.Errorf("got %d intersection tests, want %d", stats.IntersectionTests, want)
+	}
+
+	// Every ray belongs to exactly one path
+	var rays, paths int64
+	for length, n := range stats.PathLengths {
+		rays += int64(length) * n
+		paths += n
+	}
+	if rays != stats.Rays() || paths != stats.Paths() {
+		t.Errorf("path length histogram holds %d rays in %d paths, want %d in %d", rays, paths, stats.Rays(), stats.Paths())
+	}
+}
+
+// TestIntersectionTests counts the intersection tests of camera rays at a sphere filling the view, with and without holes
+func TestIntersectionTests(t *testing.T) {
+	lambertian := object.Lambertian(color.New(0.5, 0.5, 0.5))
+	for _, test := range []struct {
+		name     string
+		material object.Material
+		tests    int64 // Per camera ray
+	}{
+		// Every camera ray tests both spheres once
+		{"opaque", lambertian, 2},
+		// Every camera ray passes the front and the back of the cut-out sphere and then misses it, and misses the other one
+		{"cut out", object.Cutout(lambertian, object.Scalar(0), 0.5), 4},
+	} {
+		camera := scene.NewCamera(vector.New(0, 0, 0), vector.New(0, 0, -1), vector.New(0, 1, 0), 20, 1, 1)
+		s := scene.New(camera, 1, 8)
+		s.Spheres = append(s.Spheres,
+			object.NewSphere(vector.New(0, 0, -3), 2, test.material),
+			object.NewSphere(vector.New(0, 0, 5), 1, lambertian), // Behind the camera
+		)
+
+		var stats Stats
+		New(&s, Options{
+			SamplesPerPixel: 1,
+			MaxDepth:        1,
+			Threads:         1,
+			Filter:          film.Box(0.5),
+			Seed:            1,
+			Stats:           &stats,
+		}).Render(context.Background())
+
+		if want := stats.CameraRays * test.tests; stats.CameraRays != 64 || stats.Rays() != 64 || stats.IntersectionTests != want {
+			t.Errorf("%s: %d camera rays took %d intersection tests, want 64 rays taking %d", test.name, stats.CameraRays, stats.IntersectionTests, want)
+		}
+	}
+}
+
+// TestWhiteBalance checks that a light of the white balance temperature comes out white, in RGB and spectral renders
+func TestWhiteBalance(t *testing.T) {
+	camera := scene.NewCamera(vector.New(0, 0, 0), vector.New(0, 0, -1), vector.New(0, 1, 0), 90, 1, 1)
+	camera.WhiteBalance = 2700
+	s := scene.New(camera, 1, 4)
+	s.Spheres = append(s.Spheres, object.NewSphere(vector.New(0, 0, 0), 10, object.BlackbodyLight(2700, 2)))
+
+	for _, spectral := range []bool{false, true} {
+		f := New(&s, Options{
+			SamplesPerPixel: 20000,
+			MaxDepth:        10,
+			Threads:         1,
+			Filter:          film.Box(0.5),
+			Seed:            1,
+			Spectral:        spectral,
+		}).Render(context.Background())
+
+		c := f.Pixel(1, 1)
+		for _, channel := range []float32{c.R, c.G, c.B} {
+			if math.Abs(float64(channel)-2) > 0.1 {
+				t.Errorf("spectral=%v: a 2700 K light with strength 2 gives %v, want white of 2", spectral, c)
+				break
+			}
+		}
+	}
+}
+
+// BenchmarkFrame renders a small image of the scene full of spheres
+func BenchmarkFrame(b *testing.B) {
+	camera := scene.NewCamera(vector.New(13, 2, 3), vector.New(0, 0, 0), vector.New(0, 1, 0), 20, 16.0/9.0, 1)
+	s := scene.New(camera, 16.0/9.0, 64)
+	s.LotsOfSpheres(rand.New(rand.NewSource(1)))
+	options := Options{
+		SamplesPerPixel: 4,
+		MaxDepth:        50,
+		Threads:         runtime.NumCPU(),
+		Filter:          film.Box(0.5),
+		Seed:            1,
+	}
+
+	// The same seed traces the same rays every time, they are counted once outside of the timed renders
+	var stats Stats
+	counted := options
+	counted.Stats = &stats
+	New(&s, counted).Render(context.Background())
+
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		New(&s, options).Render(context.Background())
+	}
+	b.ReportMetric(float64(stats.Rays()*int64(b.N))/time.Since(start).Seconds()/1e6, "Mrays/s")
+}
@@ -0,0 +1,40 @@
+package render
+
+import (
+	"github.com/thijsheijden/go-raytracer/object"
+	"github.com/thijsheijden/go-raytracer/ray"
+	"github.com/thijsheijden/go-raytracer/spectrum"
+	"math/rand"
+)
+
+// spectralRay follows a ray of a single wavelength in nanometres through the scene, returning the radiance it carries back
+// Materials which do not depend on the wavelength scatter like in RGB renders, their attenuation is turned into a spectrum.
+// Emitted light and the sky are turned into spectra of light, which keep their color.
+func (r *Renderer) spectralRay(cameraRay ray.Ray, depth int, wavelength float64, random *rand.Rand, stats *Stats) float64 {
+	if depth <= 0 {
+		stats.endPath(r.Options.MaxDepth, depthLimited)
+		return 0
+	}
+	var hit object.Hit
+	var tests int64
+	hitAnything := r.Scene.CountedHit(&cameraRay, 0.001, infinity, &hit, &tests)
+	stats.traceRay(depth == r.Options.MaxDepth, tests)
+
+	if hitAnything {
+		var scattered ray.Ray
+		var attenuation float64
+
+		var emitted float64
+		if emitter, ok := hit.Material.(object.Emitter); ok {
+			emitted = spectrum.Illuminant(emitter.Emitted(&hit), wavelength)
+		}
+
+		if object.ScatterSpectral(hit.Material, &cameraRay, &hit, wavelength, &attenuation, &scattered, random) {
+			return emitted + attenuation*r.spectralRay(scattered, depth-1, wavelength, random, stats)
+		}
+		stats.endPath(r.Options.MaxDepth-depth+1, absorbed)
+		return emitted
+	}
+	stats.endPath(r.Options.MaxDepth-depth+1, escaped)
+	return spectrum.Illuminant(r.sky(cameraRay), wavelength)
+}
@@ -0,0 +1,150 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Stats counts the work done by a render, pass it in Options to collect it
+// The renderer traces no shadow rays, and the scene tests every sphere in turn instead of walking a BVH,
+// so neither is counted. Intersection tests are counted by the scene, every ray tests every sphere, and once more
+// for each hole of a masked material it passes through. The steps of random walks inside subsurface materials are
+// part of scattering a ray, they are not counted.
+type Stats struct {
+	CameraRays        int64 // Rays leaving the camera
+	ScatteredRays     int64 // Rays scattered by a material
+	IntersectionTests int64 // Ray-sphere intersection tests
+
+	Escaped      int64 // Paths which left the scene and picked up the sky color
+	Absorbed     int64 // Paths ended by a material absorbing the ray, which lights do
+	DepthLimited int64 // Paths ended by reaching the maximum depth
+
+	// Number of paths by length, the number of rays in the path. A path of only a camera ray has length 1.
+	PathLengths []int64
+}
+
+// Why a path ended
+type termination int
+
+const (
+	escaped termination = iota
+	absorbed
+	depthLimited
+)
+
+// Rays returns the total number of rays traced
+func (s *Stats) Rays() int64 {
+	return s.CameraRays + s.ScatteredRays
+}
+
+// Paths returns the number of completed paths
+func (s *Stats) Paths() int64 {
+	return s.Escaped + s.Absorbed + s.DepthLimited
+}
+
+// Add adds the counts of o to s
+func (s *Stats) Add(o *Stats) {
+	s.CameraRays += o.CameraRays
+	s.ScatteredRays += o.ScatteredRays
+	s.IntersectionTests += o.IntersectionTests
+	s.Escaped += o.Escaped
+	s.Absorbed += o.Absorbed
+	s.DepthLimited += o.DepthLimited
+	for len(s.PathLengths) < len(o.PathLengths) {
+		s.PathLengths = append(s.PathLengths, 0)
+	}
+	for length, n := range o.PathLengths {
+		s.PathLengths[length] += n
+	}
+}
+
+// traceRay counts a ray which took the given number of intersection tests, the calls are no-ops on a nil Stats
+func (s *Stats) traceRay(camera bool, tests int64) {
+	if s == nil {
+		return
+	}
+	if camera {
+		s.CameraRays++
+	} else {
+		s.ScatteredRays++
+	}
+	s.IntersectionTests += tests
+}
+
+// endPath counts a path of length rays ending for the given reason
+func (s *Stats) endPath(length int, reason termination) {
+	if s == nil {
+		return
+	}
+	switch reason {
+	case escaped:
+		s.Escaped++
+	case absorbed:
+		s.Absorbed++
+	case depthLimited:
+		s.DepthLimited++
+	}
+	for len(s.PathLengths) <= length {
+		s.PathLengths = append(s.PathLengths, 0)
+	}
+	s.PathLengths[length]++
+}
+
+// reset sets all counts back to zero, keeping the allocated histogram
+func (s *Stats) reset() {
+	pathLengths := s.PathLengths
+	for i := range pathLengths {
+		pathLengths[i] = 0
+	}
+	*s = Stats{PathLengths: pathLengths}
+}
+
+// Summary returns the counts as a human readable table with a histogram of the path lengths
+func (s *Stats) Summary() string {
+	var b strings.Builder
+	paths := s.Paths()
+	percentage := func(n int64) float64 {
+		if paths == 0 {
+			return 0
+		}
+		return 100 * float64(n) / float64(paths)
+	}
+
+	fmt.Fprintf(&b, "rays traced          %12d\n", s.Rays())
+	fmt.Fprintf(&b, "  camera             %12d\n", s.CameraRays)
+	fmt.Fprintf(&b, "  scattered          %12d\n", s.ScatteredRays)
+	fmt.Fprintf(&b, "intersection tests   %12d\n", s.IntersectionTests)
+	fmt.Fprintf(&b, "paths                %12d\n", paths)
+	fmt.Fprintf(&b, "  escaped            %12d %6.2f%%\n", s.Escaped, percentage(s.Escaped))
+	fmt.Fprintf(&b, "  absorbed           %12d %6.2f%%\n", s.Absorbed, percentage(s.Absorbed))
+	fmt.Fprintf(&b, "  depth limit        %12d %6.2f%%\n", s.DepthLimited, percentage(s.DepthLimited))
+	if paths == 0 {
+		return b.String()
+	}
+	fmt.Fprintf(&b, "mean path length     %12.2f rays\n", float64(s.Rays())/float64(paths))
+
+	// Histogram of the lengths of 99.9% of the paths, the long tail is summed up in the last bar
+	// The bars are scaled to the most common length.
+	var most int64
+	for _, n := range s.PathLengths {
+		if n > most {
+			most = n
+		}
+	}
+	const barWidth = 40
+	bar := func(label string, n int64) {
+		fmt.Fprintf(&b, "  %4s %12d %6.2f%% %s\n", label, n, percentage(n), strings.Repeat("#", int(barWidth*n/most)))
+	}
+
+	b.WriteString("path lengths\n")
+	var counted int64
+	for length := 1; length < len(s.PathLengths); length++ {
+		if float64(counted) >= 0.999*float64(paths) {
+			bar(fmt.Sprintf("%d+", length), paths-counted)
+			break
+		}
+		bar(fmt.Sprint(length), s.PathLengths[length])
+		counted += s.PathLengths[length]
+	}
+	return b.String()
+}
@@ -0,0 +1,590 @@
+package scene
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/object"
+	"github.com/thijsheijden/go-raytracer/spectrum"
+	"github.com/thijsheijden/go-raytracer/vector"
+	"math/rand"
+	"os"
+	"path/filepath"
+)
+
+// A Description describes a scene, so it can be stored as a JSON file or sent to another process
+type Description struct {
+	Camera      CameraDescription   `json:"camera"`
+	AspectRatio float64             `json:"aspectRatio"`
+	ImageWidth  int                 `json:"imageWidth"`
+	Preset      string              `json:"preset,omitempty"` // One of the built-in scenes, added before the spheres
+	Seed        int64               `json:"seed,omitempty"`   // Seed for presets with random objects
+	Spheres     []SphereDescription `json:"spheres,omitempty"`
+	Sky         *SkyDescription     `json:"sky,omitempty"`
+
+	// Directory relative paths in the description are taken from, set by Load to the directory of the scene file
+	Dir string `json:"-"`
+
+	// Contents of the files the description references, by their path as written in it, set by Embed
+	// Files which are embedded are not read from disk, so the description can be built on another machine.
+	Embedded map[string][]byte `json:"embedded,omitempty"`
+}
+
+// CameraDescription describes the camera, see NewCamera
+type CameraDescription struct {
+	Position    vector.Vector `json:"position"`
+	LookAt      vector.Vector `json:"lookAt"`
+	VUp         vector.Vector `json:"vup"`
+	VerticalFOV float64       `json:"verticalFOV"`
+	FocalLength float64       `json:"focalLength"`
+
+	// Color temperature in Kelvin which comes out white, like a white balance setting on a real camera
+	WhiteBalance float64 `json:"whiteBalance,omitempty"`
+}
+
+// SkyDescription tints the sky with a color or the color of a black body, like the light of an overcast or evening sky
+type SkyDescription struct {
+	Color       *color.RGB `json:"color,omitempty"`
+	Temperature float64    `json:"temperature,omitempty"` // In Kelvin, used instead of Color
+	Strength    *float64   `json:"strength,omitempty"`    // Multiplies the tint, 1 when missing, 0 turns the sky off
+}
+
+// SphereDescription describes a sphere
+type SphereDescription struct {
+	Center   vector.Vector       `json:"center"`
+	Radius   float64             `json:"radius"`
+	Material MaterialDescription `json:"material"`
+}
+
+// MaterialDescription describes a material, only the fields used by its type are needed
+type MaterialDescription struct {
+	// lambertian, metal, fuzzyMetal, dielectric, conductor, roughDielectric, principled, light, thinFilm, subsurface,
+	// mix, coated or twoSided
+	Type            string    `json:"type"`
+	Albedo          color.RGB `json:"albedo"`
+	Fuzziness       float64   `json:"fuzziness,omitempty"`
+	RefractionIndex float64   `json:"refractionIndex,omitempty"` // 1.5 like glass when missing, unless noted otherwise
+	Roughness       float64   `json:"roughness,omitempty"`       // Between 0 for a mirror and 1
+	Conductor       string    `json:"conductor,omitempty"`       // gold, copper or aluminium, when empty N and K are used
+	N               color.RGB `json:"n"`                         // Real part of the refractive index of a conductor
+	K               color.RGB `json:"k"`                         // Imaginary part of the refractive index of a conductor
+
+	// Color light inside a dielectric is tinted to after travelling AbsorptionDistance, no absorption when the distance is 0
+	AbsorptionColor    color.RGB `json:"absorptionColor"`
+	AbsorptionDistance float64   `json:"absorptionDistance,omitempty"`
+
+	// Refraction index per wavelength of a dielectric, used instead of RefractionIndex
+	Dispersion *DispersionDescription `json:"dispersion,omitempty"`
+
+	Principled *PrincipledDescription `json:"principled,omitempty"`
+
+	// Radiance of a light, either Emission or the color of a black body at Temperature in Kelvin, times Strength
+	Emission    *color.RGB `json:"emission,omitempty"`
+	Temperature float64    `json:"temperature,omitempty"`
+	Strength    *float64   `json:"strength,omitempty"` // 1 when missing
+
+	// Film of a thinFilm material, on a dielectric with RefractionIndex, 1 when missing like a soap bubble, or on a
+	// metal when Conductor or K is set
+	FilmThickness *TextureDescription `json:"filmThickness,omitempty"` // In nanometres, 400 when missing
+	FilmIOR       float64             `json:"filmIOR,omitempty"`       // 1.33 like soapy water when missing
+
+	// Distance light of each color typically travels inside a subsurface material, which has the color Albedo and a
+	// boundary with RefractionIndex, 1.4 when missing
+	MeanFreePath color.RGB `json:"meanFreePath"`
+
+	// Materials combined by a mix, the two blended by Weight, by coated, the base under a coat with RefractionIndex,
+	// 1.5 when missing, and CoatTint, or by twoSided, the outside and the inside
+	Materials []MaterialDescription `json:"materials,omitempty"`
+	Weight    *TextureDescription   `json:"weight,omitempty"`   // Share of the second material, 0.5 when missing
+	CoatTint  *color.RGB            `json:"coatTint,omitempty"` // White when missing
+
+	// Surface detail for any type, from a tangent space NormalMap tilted by NormalStrength, 1 when missing, or a
+	// BumpMap height moving the surface by up to BumpScale. Images for them should be marked as data.
+	NormalMap      *TextureDescription `json:"normalMap,omitempty"`
+	NormalStrength *float64            `json:"normalStrength,omitempty"`
+	BumpMap        *TextureDescription `json:"bumpMap,omitempty"`
+	BumpScale      float64             `json:"bumpScale,omitempty"`
+
+	// Mask for any type, the surface is cut away where Alpha is below AlphaThreshold, or when the threshold is missing,
+	// rays pass through with a probability of one minus Alpha
+	Alpha          *TextureDescription `json:"alpha,omitempty"`
+	AlphaThreshold *float64            `json:"alphaThreshold,omitempty"`
+}
+
+// PrincipledDescription describes the parameters of a principled material, missing parameters get their default
+// See object.PrincipledParameters for their meaning.
+type PrincipledDescription struct {
+	BaseColor          *TextureDescription `json:"baseColor,omitempty"`
+	Metallic           *TextureDescription `json:"metallic,omitempty"`
+	Roughness          *TextureDescription `json:"roughness,omitempty"`
+	Specular           *TextureDescription `json:"specular,omitempty"`
+	SpecularTint       *TextureDescription `json:"specularTint,omitempty"`
+	Sheen              *TextureDescription `json:"sheen,omitempty"`
+	SheenTint          *TextureDescription `json:"sheenTint,omitempty"`
+	Clearcoat          *TextureDescription `json:"clearcoat,omitempty"`
+	ClearcoatRoughness *TextureDescription `json:"clearcoatRoughness,omitempty"`
+	Transmission       *TextureDescription `json:"transmission,omitempty"`
+	IOR                float64             `json:"ior,omitempty"`
+}
+
+// DispersionDescription describes how the refraction index depends on the wavelength, by exactly one of its fields
+type DispersionDescription struct {
+	Glass     string    `json:"glass,omitempty"`     // bk7, fusedSilica or diamond
+	Cauchy    []float64 `json:"cauchy,omitempty"`    // A and B, with B in square micrometres
+	Sellmeier []float64 `json:"sellmeier,omitempty"` // B1, B2, B3, C1, C2 and C3, with the C coefficients in square micrometres
+}
+
+// A LoadError reports a scene file which could not be read or parsed
+type LoadError struct {
+	Path string
+	Err  error
+}
+
+func (e *LoadError) Error() string {
+	return fmt.Sprintf("loading scene %s: %v", e.Path, e.Err)
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// A DescriptionError reports a scene description which can not be built
+type DescriptionError struct {
+	Field string // The offending field, like spheres[2].material
+	Err   error
+}
+
+func (e *DescriptionError) Error() string {
+	return fmt.Sprintf("invalid scene description: %s: %v", e.Field, e.Err)
+}
+
+func (e *DescriptionError) Unwrap() error {
+	return e.Err
+}
+
+// Presets lists the built-in scenes which can be used as a preset
+var Presets = []string{"threeBalls", "glassBalls", "lotsOfSpheres"}
+
+// Load reads a scene description from a JSON file
+func Load(path string) (Description, error) {
+	var d Description
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return d, &LoadError{Path: path, Err: err}
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return d, &LoadError{Path: path, Err: err}
+	}
+	d.Dir = filepath.Dir(path)
+	return d, nil
+}
+
+// Files returns the files referenced by the description, like textures and meshes
+// Relative paths are relative to the directory of the scene file.
+func (d Description) Files() []string {
+	var files []string
+	for _, sphere := range d.Spheres {
+		files = append(files, sphere.Material.files()...)
+	}
+	return files
+}
+
+// Embed returns a copy of the description with the contents of all the files it references embedded
+func (d Description) Embed() (Description, error) {
+	source := fileSource{dir: d.Dir, embedded: d.Embedded}
+	embedded := make(map[string][]byte)
+	for _, path := range d.Files() {
+		data, err := source.read(path)
+		if err != nil {
+			return d, &DescriptionError{Field: "files", Err: err}
+		}
+		embedded[path] = data
+	}
+	d.Embedded = embedded
+	return d, nil
+}
+
+// Build creates the scene described
+func (d Description) Build() (Scene, error) {
+	if d.AspectRatio <= 0 {
+		return Scene{}, &DescriptionError{Field: "aspectRatio", Err: fmt.Errorf("must be positive, got %v", d.AspectRatio)}
+	}
+	if d.ImageWidth <= 0 {
+		return Scene{}, &DescriptionError{Field: "imageWidth", Err: fmt.Errorf("must be positive, got %d", d.ImageWidth)}
+	}
+
+	c := d.Camera
+	s := New(NewCamera(c.Position, c.LookAt, c.VUp, c.VerticalFOV, d.AspectRatio, c.FocalLength), d.AspectRatio, d.ImageWidth)
+	if s.ImageHeight <= 0 {
+		return Scene{}, &DescriptionError{Field: "imageWidth", Err: fmt.Errorf("%d is too small for aspect ratio %v", d.ImageWidth, d.AspectRatio)}
+	}
+	if c.WhiteBalance < 0 {
+		return Scene{}, &DescriptionError{Field: "camera.whiteBalance", Err: fmt.Errorf("must be positive, got %v", c.WhiteBalance)}
+	}
+	s.Camera.WhiteBalance = c.WhiteBalance
+	if sky := d.Sky; sky != nil {
+		tint, err := radiance(sky.Color, sky.Temperature, sky.Strength)
+		if err != nil {
+			return Scene{}, &DescriptionError{Field: "sky", Err: err}
+		}
+		s.SkyTint = tint
+	}
+
+	switch d.Preset {
+	case "":
+	case "threeBalls":
+		s.ThreeBalls()
+	case "glassBalls":
+		s.GlassBalls()
+	case "lotsOfSpheres":
+		s.LotsOfSpheres(rand.New(rand.NewSource(d.Seed)))
+	default:
+		return Scene{}, &DescriptionError{Field: "preset", Err: fmt.Errorf("unknown preset %q", d.Preset)}
+	}
+
+	// The files read while building are kept, to identify the scene by their contents
+	source := fileSource{dir: d.Dir, embedded: make(map[string][]byte)}
+	for path, data := range d.Embedded {
+		source.embedded[path] = data
+	}
+	for i, sphere := range d.Spheres {
+		if sphere.Radius <= 0 {
+			return Scene{}, &DescriptionError{Field: fmt.Sprintf("spheres[%d].radius", i), Err: fmt.Errorf("radius must be positive, got %v", sphere.Radius)}
+		}
+		material, err := sphere.Material.build(source)
+		if err != nil {
+			return Scene{}, &DescriptionError{Field: fmt.Sprintf("spheres[%d].material", i), Err: err}
+		}
+		s.Spheres = append(s.Spheres, object.NewSphere(sphere.Center, sphere.Radius, material))
+	}
+
+	d.Embedded = source.embedded
+	data, err := json.Marshal(d)
+	if err != nil {
+		return Scene{}, fmt.Errorf("hashing scene description: %w", err)
+	}
+	hash := sha256.Sum256(data)
+	s.descriptionHash = hex.EncodeToString(hash[:])
+	return s, nil
+}
+
+// Build creates the material described, relative texture paths are taken from the working directory
+func (d MaterialDescription) Build() (object.Material, error) {
+	return d.build(fileSource{})
+}
+
+// files returns the files referenced by the material and the materials it combines
+func (d MaterialDescription) files() []string {
+	var files []string
+	for _, t := range []*TextureDescription{d.FilmThickness, d.Weight, d.NormalMap, d.BumpMap, d.Alpha} {
+		if t != nil {
+			files = append(files, t.files()...)
+		}
+	}
+	if p := d.Principled; p != nil {
+		for _, t := range p.textures(&object.PrincipledParameters{}) {
+			if t.description != nil {
+				files = append(files, t.description.files()...)
+			}
+		}
+	}
+	for _, m := range d.Materials {
+		files = append(files, m.files()...)
+	}
+	return files
+}
+
+func (d MaterialDescription) build(source fileSource) (object.Material, error) {
+	material, err := d.buildDetailed(source)
+	if err != nil || d.Alpha == nil {
+		return material, err
+	}
+	alpha, err := d.Alpha.build(source)
+	if err != nil {
+		return nil, fmt.Errorf("alpha: %w", err)
+	}
+	if d.AlphaThreshold == nil {
+		return object.PartiallyOpaque(material, alpha), nil
+	}
+	return object.Cutout(material, alpha, *d.AlphaThreshold), nil
+}
+
+// buildDetailed creates the material with its normal or bump map, without the alpha mask
+func (d MaterialDescription) buildDetailed(source fileSource) (object.Material, error) {
+	material, err := d.buildSurface(source)
+	if err != nil {
+		return nil, err
+	}
+	if d.NormalMap != nil && d.BumpMap != nil {
+		return nil, errors.New("a material can not have both a normal map and a bump map")
+	}
+	if d.NormalMap != nil {
+		normals, err := d.NormalMap.build(source)
+		if err != nil {
+			return nil, fmt.Errorf("normalMap: %w", err)
+		}
+		strength := 1.0
+		if d.NormalStrength != nil {
+			strength = *d.NormalStrength
+		}
+		return object.NormalMap(material, normals, strength), nil
+	}
+	if d.BumpMap != nil {
+		height, err := d.BumpMap.build(source)
+		if err != nil {
+			return nil, fmt.Errorf("bumpMap: %w", err)
+		}
+		return object.BumpMap(material, height, d.BumpScale), nil
+	}
+	return material, nil
+}
+
+// buildSurface creates the material of the type described, without normal or bump map
+func (d MaterialDescription) buildSurface(source fileSource) (object.Material, error) {
+	if d.RefractionIndex < 0 {
+		return nil, fmt.Errorf("refractionIndex must be positive, got %v", d.RefractionIndex)
+	}
+
+	switch d.Type {
+	case "lambertian":
+		return object.Lambertian(d.Albedo), nil
+	case "metal":
+		return object.Metal(d.Albedo), nil
+	case "fuzzyMetal":
+		return object.FuzzyMetal(d.Albedo, d.Fuzziness), nil
+	case "dielectric":
+		if d.AbsorptionDistance < 0 {
+			return nil, fmt.Errorf("absorptionDistance must be positive, got %v", d.AbsorptionDistance)
+		}
+		if d.Dispersion != nil {
+			if d.AbsorptionDistance > 0 {
+				return nil, errors.New("a dielectric can not have both dispersion and absorption")
+			}
+			ior, err := d.Dispersion.build()
+			if err != nil {
+				return nil, err
+			}
+			return object.DispersiveDielectric(ior), nil
+		}
+		if d.AbsorptionDistance > 0 {
+			c := d.AbsorptionColor
+			if c.R <= 0 || c.G <= 0 || c.B <= 0 || c.R > 1 || c.G > 1 || c.B > 1 {
+				return nil, fmt.Errorf("absorptionColor channels must be above 0 and at most 1, got %v", c)
+			}
+			return object.AbsorbingDielectric(d.glassIOR(), c, d.AbsorptionDistance), nil
+		}
+		return object.Dielectric(d.glassIOR()), nil
+	case "conductor":
+		if d.Conductor == "" {
+			return object.Conductor(d.N, d.K, d.Roughness), nil
+		}
+		return object.ConductorByName(d.Conductor, d.Roughness)
+	case "roughDielectric":
+		return object.RoughDielectric(d.glassIOR(), d.Roughness), nil
+	case "principled":
+		if d.Principled == nil {
+			return object.Principled(object.PrincipledParameters{}), nil
+		}
+		return d.Principled.build(source)
+	case "thinFilm":
+		return d.buildThinFilm(source)
+	case "subsurface":
+		if d.MeanFreePath.R <= 0 || d.MeanFreePath.G <= 0 || d.MeanFreePath.B <= 0 {
+			return nil, fmt.Errorf("meanFreePath channels must be positive, got %v", d.MeanFreePath)
+		}
+		ior := d.RefractionIndex
+		if ior == 0 {
+			ior = 1.4
+		}
+		return object.Subsurface(d.Albedo, d.MeanFreePath, ior), nil
+	case "mix", "coated", "twoSided":
+		return d.buildCombination(source)
+	case "light":
+		emission, err := radiance(d.Emission, d.Temperature, d.Strength)
+		if err != nil {
+			return nil, err
+		}
+		return object.Light(emission), nil
+	}
+	return nil, fmt.Errorf("unknown material type %q", d.Type)
+}
+
+// glassIOR returns the refraction index of a dielectric, or of a coat, 1.5 like glass when it is missing
+func (d MaterialDescription) glassIOR() float64 {
+	if d.RefractionIndex == 0 {
+		return 1.5
+	}
+	return d.RefractionIndex
+}
+
+// buildCombination creates a material combining others
+func (d MaterialDescription) buildCombination(source fileSource) (object.Material, error) {
+	want := 2
+	if d.Type == "coated" {
+		want = 1
+	}
+	if len(d.Materials) != want {
+		return nil, fmt.Errorf("%s needs %d materials, got %d", d.Type, want, len(d.Materials))
+	}
+	materials := make([]object.Material, len(d.Materials))
+	for i, description := range d.Materials {
+		material, err := description.build(source)
+		if err != nil {
+			return nil, fmt.Errorf("materials[%d]: %w", i, err)
+		}
+		materials[i] = material
+	}
+
+	switch d.Type {
+	case "mix":
+		weight := object.Scalar(0.5)
+		if d.Weight != nil {
+			var err error
+			if weight, err = d.Weight.build(source); err != nil {
+				return nil, fmt.Errorf("weight: %w", err)
+			}
+		}
+		return object.Mix(materials[0], materials[1], weight), nil
+	case "coated":
+		ior := d.glassIOR()
+		tint := color.New(1, 1, 1)
+		if d.CoatTint != nil {
+			tint = *d.CoatTint
+		}
+		return object.Coated(materials[0], ior, tint), nil
+	}
+	return object.TwoSided(materials[0], materials[1]), nil
+}
+
+// buildThinFilm creates the thin film material described
+func (d MaterialDescription) buildThinFilm(source fileSource) (object.Material, error) {
+	var thickness object.Texture
+	if d.FilmThickness != nil {
+		var err error
+		if thickness, err = d.FilmThickness.build(source); err != nil {
+			return nil, fmt.Errorf("filmThickness: %w", err)
+		}
+	}
+	if d.FilmIOR < 0 || d.RefractionIndex < 0 {
+		return nil, fmt.Errorf("filmIOR and refractionIndex must be positive, got %v and %v", d.FilmIOR, d.RefractionIndex)
+	}
+	filmIOR := d.FilmIOR
+	if filmIOR == 0 {
+		filmIOR = 1.33
+	}
+
+	if d.Conductor != "" {
+		n, k, err := object.ConductorIOR(d.Conductor)
+		if err != nil {
+			return nil, err
+		}
+		return object.ThinFilmConductor(thickness, filmIOR, n, k), nil
+	}
+	if d.K != (color.RGB{}) {
+		return object.ThinFilmConductor(thickness, filmIOR, d.N, d.K), nil
+	}
+	baseIOR := d.RefractionIndex
+	if baseIOR == 0 {
+		baseIOR = 1
+	}
+	return object.ThinFilm(thickness, filmIOR, baseIOR), nil
+}
+
+// radiance combines a color or the temperature of a black body with a strength, white with strength 1 when all are missing
+func radiance(c *color.RGB, temperature float64, strength *float64) (color.RGB, error) {
+	if c != nil && temperature != 0 {
+		return color.RGB{}, errors.New("a color and a temperature can not be combined")
+	}
+	if temperature < 0 {
+		return color.RGB{}, fmt.Errorf("temperature must be positive, got %v", temperature)
+	}
+	if strength != nil && *strength < 0 {
+		return color.RGB{}, fmt.Errorf("strength must be positive, got %v", *strength)
+	}
+
+	result := color.New(1, 1, 1)
+	if c != nil {
+		result = *c
+	} else if temperature > 0 {
+		result = spectrum.Blackbody(temperature)
+	}
+	if strength != nil {
+		result = result.Scale(float32(*strength))
+	}
+	return result, nil
+}
+
+// build creates the refraction index described
+func (d *DispersionDescription) build() (spectrum.IOR, error) {
+	set := 0
+	for _, isSet := range []bool{d.Glass != "", d.Cauchy != nil, d.Sellmeier != nil} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, errors.New("dispersion needs exactly one of glass, cauchy and sellmeier")
+	}
+
+	switch {
+	case d.Glass != "":
+		return spectrum.GlassByName(d.Glass)
+	case d.Cauchy != nil:
+		if len(d.Cauchy) != 2 {
+			return nil, fmt.Errorf("cauchy needs 2 coefficients, got %d", len(d.Cauchy))
+		}
+		return spectrum.Cauchy(d.Cauchy[0], d.Cauchy[1]), nil
+	default:
+		if len(d.Sellmeier) != 6 {
+			return nil, fmt.Errorf("sellmeier needs 6 coefficients, got %d", len(d.Sellmeier))
+		}
+		s := d.Sellmeier
+		return spectrum.Sellmeier([3]float64{s[0], s[1], s[2]}, [3]float64{s[3], s[4], s[5]}), nil
+	}
+}
+
+// A textured parameter of the principled material
+type principledTexture struct {
+	name        string
+	description *TextureDescription
+	texture     *object.Texture // Where the built texture goes
+}
+
+// textures returns the textured parameters, which are built into p
+func (d *PrincipledDescription) textures(p *object.PrincipledParameters) []principledTexture {
+	return []principledTexture{
+		{"baseColor", d.BaseColor, &p.BaseColor},
+		{"metallic", d.Metallic, &p.Metallic},
+		{"roughness", d.Roughness, &p.Roughness},
+		{"specular", d.Specular, &p.Specular},
+		{"specularTint", d.SpecularTint, &p.SpecularTint},
+		{"sheen", d.Sheen, &p.Sheen},
+		{"sheenTint", d.SheenTint, &p.SheenTint},
+		{"clearcoat", d.Clearcoat, &p.Clearcoat},
+		{"clearcoatRoughness", d.ClearcoatRoughness, &p.ClearcoatRoughness},
+		{"transmission", d.Transmission, &p.Transmission},
+	}
+}
+
+// build creates the principled material described
+func (d *PrincipledDescription) build(source fileSource) (object.Material, error) {
+	if d.IOR < 0 {
+		return nil, fmt.Errorf("ior must be positive, got %v", d.IOR)
+	}
+
+	parameters := object.PrincipledParameters{IOR: d.IOR}
+	for _, t := range d.textures(&parameters) {
+		if t.description == nil {
+			continue
+		}
+		texture, err := t.description.build(source)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.name, err)
+		}
+		*t.texture = texture
+	}
+	return object.Principled(parameters), nil
+}
@@ -1,12 +1,16 @@
 package scene
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/object"
+	"github.com/thijsheijden/go-raytracer/ray"
+	"github.com/thijsheijden/go-raytracer/spectrum"
+	"github.com/thijsheijden/go-raytracer/vector"
 	"math"
 	"math/rand"
-	"raytracer/internal/color"
-	"raytracer/internal/object"
-	"raytracer/internal/ray"
-	"raytracer/internal/vector"
 )
 
 // A Scene contains all objects
@@ -20,6 +24,9 @@ type Scene struct {
 	Spheres                           []*object.Sphere
 	ImageHeight, ImageWidth           int
 	FloatImageHeight, FloatImageWidth float64
+	SkyTint                           color.RGB // Multiplies the sky gradient, white leaves it as it is
+
+	descriptionHash string // Hash of the description the scene was built from, with the contents of its files
 }
 
 // Camera is the camera
@@ -31,6 +38,23 @@ type Camera struct {
 	FocalLength    float64
 	ViewportWidth  float64
 	ViewportHeight float64
+	WhiteBalance   float64 // Color temperature in Kelvin which comes out white, 0 leaves the colors as they are
+}
+
+// WhiteBalanceGain returns the factors for each color channel which neutralise the white balance temperature
+func (c Camera) WhiteBalanceGain() color.RGB {
+	if c.WhiteBalance == 0 {
+		return color.New(1, 1, 1)
+	}
+	// Channels a very low temperature has no light in are left alone instead of amplified without bound
+	white := spectrum.Blackbody(c.WhiteBalance)
+	gain := func(channel float32) float32 {
+		if channel < 0.01 {
+			return 1
+		}
+		return 1 / channel
+	}
+	return color.New(gain(white.R), gain(white.G), gain(white.B))
 }
 
 // New creates a new scene
@@ -57,6 +81,7 @@ func New(camera Camera, aspectRatio float64, imageWidth int) Scene {
 		FloatImageWidth:  float64(imageWidth),
 		FloatImageHeight: float64(imageHeight),
 		Spheres:          make([]*object.Sphere, 0),
+		SkyTint:          color.New(1, 1, 1),
 	}
 }
 
@@ -113,23 +138,24 @@ func (s *Scene) GlassBalls() {
 }
 
 // LotsOfSpheres generates a scene with many, many randomly placed and materialised spheres
-func (s *Scene) LotsOfSpheres() {
+// The same random generator seed always produces the same scene
+func (s *Scene) LotsOfSpheres(random *rand.Rand) {
 	groundMaterial := object.Lambertian(color.New(0.5, 0.5, 0.5))
 	s.Spheres = append(s.Spheres, object.NewSphere(vector.New(0, -1000, 0), 1000, groundMaterial))
 
 	for a := -11.0; a < 11; a++ {
 		for b := -11.0; b < 11; b++ {
-			chooseMat := rand.Float64()
-			center := vector.New(a+0.9*rand.Float64(), 0.2, b+0.9*rand.Float64())
+			chooseMat := random.Float64()
+			center := vector.New(a+0.9*random.Float64(), 0.2, b+0.9*random.Float64())
 			if center.Sub(vector.New(4, 0.2, 0)).Length() > 0.9 {
 
 				if chooseMat < 0.8 {
-					s.Spheres = append(s.Spheres, object.NewSphere(center, 0.2, object.Lambertian(color.Random())))
+					s.Spheres = append(s.Spheres, object.NewSphere(center, 0.2, object.Lambertian(color.Random(random))))
 					continue
 				}
 
 				if chooseMat < 0.95 {
-					s.Spheres = append(s.Spheres, object.NewSphere(center, 0.2, object.FuzzyMetal(color.RandomInRange(0.5, 1), randomInRange(0, 0.5))))
+					s.Spheres = append(s.Spheres, object.NewSphere(center, 0.2, object.FuzzyMetal(color.RandomInRange(0.5, 1, random), randomInRange(0, 0.5, random))))
 					continue
 				}
 
@@ -143,23 +169,59 @@ func (s *Scene) LotsOfSpheres() {
 	s.Spheres = append(s.Spheres, object.NewSphere(vector.New(4, 1, 0), 1.0, object.Metal(color.New(0.7, 0.6, 0.5))))
 }
 
+// Hash returns a hash identifying the camera, image size and all objects of the scene
+// A scene built from a Description is identified by the description and the contents of the files it uses. Materials
+// can not be looked into otherwise, so of a scene built in Go, or spheres added to it, only their types count.
+func (s *Scene) Hash() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %#v %d %d %#v\n", s.descriptionHash, s.Camera, s.ImageWidth, s.ImageHeight, s.SkyTint)
+	for _, sphere := range s.Spheres {
+		fmt.Fprintf(h, "%#v %v %T\n", sphere.Center, sphere.Radius, sphere.Material)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// CameraRay returns the ray leaving the camera through viewport position (u, v)
+// u runs from left to right and v from bottom to top, both between 0 and 1
+func (s *Scene) CameraRay(u, v float64) ray.Ray {
+	return ray.New(s.Origin, s.LowerLeftCorner.Add(s.Horizontal.Scale(u)).Add(s.Vertical.Scale(v)).Sub(s.Origin))
+}
+
 // Hit checks for hits in the scene
+// Hits on the holes of a masked material are skipped, the ray goes on to the next surface behind them.
 func (s *Scene) Hit(r *ray.Ray, tMin, tMax float64, hit *object.Hit) bool {
+	var tests int64
+	return s.CountedHit(r, tMin, tMax, hit, &tests)
+}
+
+// CountedHit checks for hits in the scene like Hit, adding the number of ray-sphere intersection tests to tests
+func (s *Scene) CountedHit(r *ray.Ray, tMin, tMax float64, hit *object.Hit, tests *int64) bool {
 	var tempHit object.Hit
 	hitAnything := false
 	closestSoFar := tMax
 
 	for _, sphere := range s.Spheres {
-		if sphere.Intersect(r, tMin, closestSoFar, &tempHit) {
+		from := tMin
+		for {
+			*tests++
+			if !sphere.Intersect(r, from, closestSoFar, &tempHit) {
+				break
+			}
+			if m, ok := tempHit.Material.(object.MaskedMaterial); ok && !m.Visible(r, &tempHit) {
+				// Look for the next hit on the same sphere, just past this one
+				from = math.Nextafter(tempHit.T, math.Inf(1))
+				continue
+			}
 			hitAnything = true
 			closestSoFar = tempHit.T
 			*hit = tempHit
+			break
 		}
 	}
 
 	return hitAnything
 }
 
-func randomInRange(min, max float64) float64 {
-	return min + rand.Float64()*(max-min)
+func randomInRange(min, max float64, random *rand.Rand) float64 {
+	return min + random.Float64()*(max-min)
 }
@@ -0,0 +1,187 @@
+package scene
+
+import (
+	"errors"
+	"fmt"
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/object"
+	"github.com/thijsheijden/go-raytracer/ray"
+	"github.com/thijsheijden/go-raytracer/vector"
+	"image"
+	"image/png"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Stored so the compiler can not optimise the benchmarked calls away
+var sinkBool bool
+
+func TestDescriptionDefaults(t *testing.T) {
+	for _, test := range []struct {
+		material MaterialDescription
+		want     object.Material
+	}{
+		{MaterialDescription{Type: "dielectric"}, object.Dielectric(1.5)},
+		{MaterialDescription{Type: "roughDielectric", Roughness: 0.3}, object.RoughDielectric(1.5, 0.3)},
+		{MaterialDescription{Type: "thinFilm"}, object.ThinFilm(nil, 1.33, 1)},
+	} {
+		got, err := test.material.Build()
+		if err != nil {
+			t.Errorf("%s: %v", test.material.Type, err)
+		} else if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %#v, want %#v", test.material.Type, got, test.want)
+		}
+	}
+
+	invalid := map[string]Description{
+		"refraction index": {Spheres: []SphereDescription{{Radius: 1, Material: MaterialDescription{Type: "dielectric", RefractionIndex: -1.5}}}},
+		"radius":           {Spheres: []SphereDescription{{Radius: 0, Material: MaterialDescription{Type: "lambertian"}}}},
+	}
+	for name, d := range invalid {
+		d.AspectRatio, d.ImageWidth = 1, 10
+		d.Camera = CameraDescription{LookAt: vector.New(0, 0, -1), VUp: vector.New(0, 1, 0), VerticalFOV: 90, FocalLength: 1}
+		var descriptionError *DescriptionError
+		if _, err := d.Build(); !errors.As(err, &descriptionError) {
+			t.Errorf("invalid %s: got error %v, want a DescriptionError", name, err)
+		}
+	}
+}
+
+// TestHash checks that a scene built from a description is identified by the description and its files
+func TestHash(t *testing.T) {
+	dir := t.TempDir()
+	texture := filepath.Join(dir, "texture.png")
+	writeImage := func(c uint8) {
+		img := image.NewGray(image.Rect(0, 0, 1, 1))
+		img.Pix[0] = c
+		file, err := os.Create(texture)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if err := png.Encode(file, img); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d := Description{
+		Camera:      CameraDescription{LookAt: vector.New(0, 0, -1), VUp: vector.New(0, 1, 0), VerticalFOV: 90, FocalLength: 1},
+		AspectRatio: 1,
+		ImageWidth:  10,
+		Spheres: []SphereDescription{{Center: vector.New(0, 0, -1), Radius: 0.5, Material: MaterialDescription{
+			Type:       "principled",
+			Principled: &PrincipledDescription{BaseColor: &TextureDescription{Type: "image", Path: "texture.png"}},
+		}}},
+		Dir: dir,
+	}
+	hash := func(d Description) string {
+		s, err := d.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.Hash()
+	}
+
+	writeImage(100)
+	first := hash(d)
+	if again := hash(d); again != first {
+		t.Errorf("building the same description twice gives hashes %s and %s", first, again)
+	}
+	embedded, err := d.Embed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := hash(embedded); h != first {
+		t.Errorf("embedding the files changes the hash from %s to %s", first, h)
+	}
+
+	writeImage(200)
+	second := hash(d)
+	if second == first {
+		t.Error("changing the texture keeps the hash")
+	}
+	d.Spheres[0].Radius = 0.6
+	if hash(d) == second {
+		t.Error("changing the radius keeps the hash")
+	}
+}
+
+func TestMaskedHit(t *testing.T) {
+	lambertian := object.Lambertian(color.New(0.5, 0.5, 0.5))
+	behind := object.NewSphere(vector.New(0, 0, -5), 1, lambertian)
+	cutOut := object.Cutout(lambertian, object.Scalar(0), 0.5)
+	newScene := func(front object.Material) Scene {
+		s := New(NewCamera(vector.New(0, 0, 0), vector.New(0, 0, -1), vector.New(0, 1, 0), 90, 1, 1), 1, 100)
+		s.Spheres = []*object.Sphere{object.NewSphere(vector.New(0, 0, -2), 0.5, front), behind}
+		return s
+	}
+	r := ray.New(vector.New(0, 0, 0), vector.New(0, 0, -1))
+
+	tests := []struct {
+		name  string
+		front object.Material
+		want  float64
+	}{
+		{"opaque", object.Cutout(lambertian, object.Scalar(1), 0.5), 1.5},
+		{"cut out", object.Cutout(lambertian, object.Scalar(0.2), 0.5), 4},
+		{"invisible", object.PartiallyOpaque(lambertian, object.Scalar(0)), 4},
+		{"normal mapped", object.NormalMap(cutOut, object.Constant(color.New(0.5, 0.5, 1)), 1), 4},
+		{"mixed", object.Mix(cutOut, cutOut, object.Scalar(0.5)), 4},
+		{"coated", object.Coated(cutOut, 1.5, color.New(1, 1, 1)), 4},
+		{"two sided", object.TwoSided(cutOut, lambertian), 2.5}, // Through the front, onto the inside of the back
+	}
+	for _, test := range tests {
+		s := newScene(test.front)
+		var hit object.Hit
+		if !s.Hit(&r, 0.001, 1e9, &hit) {
+			t.Errorf("%s: ray hits nothing", test.name)
+		} else if math.Abs(hit.T-test.want) > 1e-9 {
+			t.Errorf("%s: ray hits at %v, want %v", test.name, hit.T, test.want)
+		}
+	}
+
+	// A partially opaque surface stops a share of the rays given by its alpha
+	s := newScene(object.PartiallyOpaque(lambertian, object.Scalar(0.3)))
+	random := rand.New(rand.NewSource(1))
+	const n = 10000
+	stopped := 0
+	for i := 0; i < n; i++ {
+		r := ray.New(vector.New(0, 0, 0), vector.New(randomInRange(-0.01, 0.01, random), randomInRange(-0.01, 0.01, random), -1))
+		var hit object.Hit
+		if s.Hit(&r, 0.001, 1e9, &hit) && hit.T < 3 {
+			stopped++
+		}
+	}
+	// Rays which pass the front of the sphere can still hit its back
+	if want := 1 - 0.7*0.7; math.Abs(float64(stopped)/n-want) > 0.02 {
+		t.Errorf("partially opaque sphere stops %v of the rays, want %v", float64(stopped)/n, want)
+	}
+}
+
+func BenchmarkHit(b *testing.B) {
+	for _, n := range []int{1, 10, 100, 1000} {
+		b.Run(fmt.Sprintf("spheres=%d", n), func(b *testing.B) {
+			random := rand.New(rand.NewSource(1))
+			s := New(NewCamera(vector.New(0, 0, 0), vector.New(0, 0, -1), vector.New(0, 1, 0), 90, 1, 1), 1, 100)
+			for i := 0; i < n; i++ {
+				center := vector.New(randomInRange(-10, 10, random), randomInRange(-10, 10, random), randomInRange(-20, -2, random))
+				s.Spheres = append(s.Spheres, object.NewSphere(center, 0.5, object.Lambertian(color.New(0.5, 0.5, 0.5))))
+			}
+
+			// Rays through the whole viewport, some hit and some miss
+			rays := make([]ray.Ray, 256)
+			for i := range rays {
+				rays[i] = s.CameraRay(random.Float64(), random.Float64())
+			}
+
+			b.ResetTimer()
+			var hit object.Hit
+			for i := 0; i < b.N; i++ {
+				sinkBool = s.Hit(&rays[i%len(rays)], 0.001, 1e9, &hit)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package scene
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/object"
+	"image"
+	_ "image/jpeg" // Decoders for image textures
+	_ "image/png"
+	"os"
+	"path/filepath"
+)
+
+// TextureDescription describes a texture
+// In JSON a constant texture can also be written as just a number, for a gray value, or as a color like {"r": 1, "g": 0.5, "b": 0}.
+type TextureDescription struct {
+	Type  string              `json:"type"` // constant, checker or image
+	Color color.RGB           `json:"color"`
+	Even  *TextureDescription `json:"even,omitempty"`  // First texture of a checker
+	Odd   *TextureDescription `json:"odd,omitempty"`   // Second texture of a checker
+	Scale float64             `json:"scale,omitempty"` // Number of checker squares per unit
+	Path  string              `json:"path,omitempty"`  // PNG or JPEG image, relative to the scene file
+
+	// The image holds data like normals or heights instead of sRGB colors, its values are used as they are
+	Data bool `json:"data,omitempty"`
+
+	// The texture is the alpha channel of the image in gray, for the alpha of a cut-out material
+	AlphaChannel bool `json:"alphaChannel,omitempty"`
+}
+
+// UnmarshalJSON also accepts the short forms of a constant texture
+func (t *TextureDescription) UnmarshalJSON(data []byte) error {
+	var v float32
+	if err := json.Unmarshal(data, &v); err == nil {
+		*t = TextureDescription{Type: "constant", Color: color.New(v, v, v)}
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("a texture must be a number, a color or an object with a type: %w", err)
+	}
+	if _, ok := fields["type"]; !ok {
+		var c color.RGB
+		if err := json.Unmarshal(data, &c); err != nil {
+			return err
+		}
+		*t = TextureDescription{Type: "constant", Color: c}
+		return nil
+	}
+
+	// A type without methods, so this method is not called again
+	type plain TextureDescription
+	return json.Unmarshal(data, (*plain)(t))
+}
+
+// build creates the texture described, reading images from source
+func (d *TextureDescription) build(source fileSource) (object.Texture, error) {
+	switch d.Type {
+	case "constant":
+		return object.Constant(d.Color), nil
+	case "checker":
+		if d.Even == nil || d.Odd == nil {
+			return nil, fmt.Errorf("a checker needs an even and an odd texture")
+		}
+		even, err := d.Even.build(source)
+		if err != nil {
+			return nil, err
+		}
+		odd, err := d.Odd.build(source)
+		if err != nil {
+			return nil, err
+		}
+		if d.Scale <= 0 {
+			return nil, fmt.Errorf("checker scale must be positive, got %v", d.Scale)
+		}
+		return object.Checker(even, odd, d.Scale), nil
+	case "image":
+		img, err := source.readImage(d.Path)
+		if err != nil {
+			return nil, err
+		}
+		if d.AlphaChannel {
+			return object.ImageAlpha(img), nil
+		}
+		if d.Data {
+			return object.ImageData(img), nil
+		}
+		return object.Image(img), nil
+	}
+	return nil, fmt.Errorf("unknown texture type %q", d.Type)
+}
+
+// files returns the image files used by the texture
+func (d *TextureDescription) files() []string {
+	switch d.Type {
+	case "checker":
+		var files []string
+		if d.Even != nil {
+			files = append(files, d.Even.files()...)
+		}
+		if d.Odd != nil {
+			files = append(files, d.Odd.files()...)
+		}
+		return files
+	case "image":
+		return []string{d.Path}
+	}
+	return nil
+}
+
+// A fileSource gives the files a description references, by their path as written in the description
+type fileSource struct {
+	dir string // Directory relative paths are taken from
+
+	// Contents used instead of the files on disk, see Description.Embed. Files read from disk are added to it.
+	embedded map[string][]byte
+}
+
+// read returns the contents of the file at path
+func (s fileSource) read(path string) ([]byte, error) {
+	if data, ok := s.embedded[path]; ok {
+		return data, nil
+	}
+	data, err := os.ReadFile(resolvePath(s.dir, path))
+	if err == nil && s.embedded != nil {
+		s.embedded[path] = data
+	}
+	return data, err
+}
+
+func (s fileSource) readImage(path string) (image.Image, error) {
+	data, err := s.read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("reading texture %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// resolvePath makes a path relative to dir, unless it is absolute
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) || dir == "" {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
@@ -0,0 +1,51 @@
+package scene
+
+import (
+	"encoding/json"
+	"github.com/thijsheijden/go-raytracer/color"
+	"reflect"
+	"testing"
+)
+
+func TestTextureDescriptionJSON(t *testing.T) {
+	tests := []struct {
+		json string
+		want TextureDescription
+	}{
+		{`0.25`, TextureDescription{Type: "constant", Color: color.New(0.25, 0.25, 0.25)}},
+		{`{"r": 1, "g": 0.5, "b": 0}`, TextureDescription{Type: "constant", Color: color.New(1, 0.5, 0)}},
+		{`{"type": "image", "path": "wood.png"}`, TextureDescription{Type: "image", Path: "wood.png"}},
+		{`{"type": "checker", "even": 0, "odd": {"r": 1, "g": 1, "b": 1}, "scale": 2}`, TextureDescription{
+			Type:  "checker",
+			Even:  &TextureDescription{Type: "constant"},
+			Odd:   &TextureDescription{Type: "constant", Color: color.New(1, 1, 1)},
+			Scale: 2,
+		}},
+	}
+
+	for _, test := range tests {
+		var got TextureDescription
+		if err := json.Unmarshal([]byte(test.json), &got); err != nil {
+			t.Errorf("%s: %v", test.json, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.json, got, test.want)
+		}
+
+		// Descriptions are sent to workers as JSON, so the long form has to read back the same
+		data, err := json.Marshal(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var again TextureDescription
+		if err := json.Unmarshal(data, &again); err != nil || !reflect.DeepEqual(again, got) {
+			t.Errorf("%s: marshalled as %s, which reads back as %+v, %v", test.json, data, again, err)
+		}
+	}
+
+	var invalid TextureDescription
+	if err := json.Unmarshal([]byte(`"red"`), &invalid); err == nil {
+		t.Error("a string is accepted as a texture")
+	}
+}
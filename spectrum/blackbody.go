@@ -0,0 +1,32 @@
+package spectrum
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"math"
+)
+
+// planck returns the spectral radiance of a black body at a temperature in Kelvin, for a wavelength in nanometres
+// The constant factors are left out, only the shape of the spectrum matters.
+func planck(wavelength, kelvin float64) float64 {
+	const c2 = 1.4387769e7 // Second radiation constant hc/k, in nanometre Kelvin
+	l5 := math.Pow(wavelength, 5)
+	return 1 / (l5 * (math.Exp(c2/(wavelength*kelvin)) - 1))
+}
+
+// Blackbody returns the linear sRGB color of the light a black body at a temperature in Kelvin emits
+// The color has a luminance of 1, so it only sets the tint and a strength can be applied separately. Around 6500 K
+// the color is white, lower temperatures are orange like candles and tungsten bulbs, higher ones are blue like an
+// overcast sky. Colors outside the sRGB gamut, below about 1500 K, are clipped.
+func Blackbody(kelvin float64) color.RGB {
+	const step = 1.0
+	var x, y, z float64
+	for wavelength := MinWavelength; wavelength <= MaxWavelength; wavelength += step {
+		b := planck(wavelength, kelvin)
+		dx, dy, dz := matching(wavelength)
+		x += dx * b
+		y += dy * b
+		z += dz * b
+	}
+	r, g, b := xyzToRGB(x/y, 1, z/y)
+	return color.New(float32(math.Max(r, 0)), float32(math.Max(g, 0)), float32(math.Max(b, 0)))
+}
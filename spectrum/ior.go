@@ -0,0 +1,75 @@
+package spectrum
+
+import (
+	"fmt"
+	"math"
+)
+
+// Wavelength of the helium d line in nanometres, at which refraction indices are usually quoted
+const HeliumD = 587.56
+
+// An IOR gives the refraction index of a material for every wavelength in nanometres
+type IOR interface {
+	At(wavelength float64) float64
+}
+
+// Cauchy's equation n = A + B / λ², with λ in micrometres
+type cauchy struct {
+	a, b float64
+}
+
+// Cauchy returns the refraction index following Cauchy's equation, with b in square micrometres
+// It fits glasses well in the visible range, a larger b disperses light more.
+func Cauchy(a, b float64) IOR {
+	return cauchy{
+		a: a,
+		b: b,
+	}
+}
+
+func (c cauchy) At(wavelength float64) float64 {
+	micrometres := wavelength / 1000
+	return c.a + c.b/(micrometres*micrometres)
+}
+
+// The Sellmeier equation n² = 1 + Σ Bᵢλ² / (λ² - Cᵢ), with λ in micrometres
+type sellmeier struct {
+	b, c [3]float64
+}
+
+// Sellmeier returns the refraction index following the Sellmeier equation, with the c coefficients in square
+// micrometres. Glass manufacturers publish these coefficients for their glasses.
+func Sellmeier(b, c [3]float64) IOR {
+	return sellmeier{
+		b: b,
+		c: c,
+	}
+}
+
+func (s sellmeier) At(wavelength float64) float64 {
+	l2 := wavelength * wavelength / 1e6
+	n2 := 1.0
+	for i := range s.b {
+		n2 += s.b[i] * l2 / (l2 - s.c[i])
+	}
+	return math.Sqrt(n2)
+}
+
+// Measured Sellmeier coefficients
+var glasses = map[string]IOR{
+	"bk7":         Sellmeier([3]float64{1.03961212, 0.231792344, 1.01046945}, [3]float64{0.00600069867, 0.0200179144, 103.560653}),
+	"fusedSilica": Sellmeier([3]float64{0.6961663, 0.4079426, 0.8974794}, [3]float64{0.0684043 * 0.0684043, 0.1162414 * 0.1162414, 9.896161 * 9.896161}),
+	"diamond":     Sellmeier([3]float64{0.3306, 4.3356, 0}, [3]float64{0.1750 * 0.1750, 0.1060 * 0.1060, 0}),
+}
+
+// Glasses lists the names accepted by GlassByName
+var Glasses = []string{"bk7", "fusedSilica", "diamond"}
+
+// GlassByName returns the refraction index of a common optical material
+func GlassByName(name string) (IOR, error) {
+	ior, ok := glasses[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown glass %q", name)
+	}
+	return ior, nil
+}
@@ -0,0 +1,110 @@
+// Package spectrum converts between RGB colors and spectra, for rendering with wavelengths instead of RGB.
+//
+// A spectral render traces every path at a single wavelength. The radiance it carries back is converted to RGB
+// with the CIE 1931 color matching functions, and averaging many of them gives the color of the pixel.
+package spectrum
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"math"
+)
+
+// Range of wavelengths sampled, in nanometres
+const (
+	MinWavelength = 380.0
+	MaxWavelength = 720.0
+)
+
+// SampleWavelength maps u between 0 and 1 uniformly onto the visible wavelengths
+func SampleWavelength(u float64) float64 {
+	return MinWavelength + u*(MaxWavelength-MinWavelength)
+}
+
+// ToRGB returns the linear sRGB contribution of radiance at a wavelength picked by SampleWavelength
+// A flat spectrum averages out to white, so colors match those of RGB renders.
+func ToRGB(wavelength, radiance float64) color.RGB {
+	x, y, z := tabulated(wavelength)
+	scale := radiance * (MaxWavelength - MinWavelength) / integralY
+	r, g, b := xyzToRGB(x*scale, y*scale, z*scale)
+	return color.New(float32(r/white[0]), float32(g/white[1]), float32(b/white[2]))
+}
+
+// matching returns the CIE 1931 2° color matching functions at a wavelength
+// This is the multi-lobe fit from "Simple Analytic Approximations to the CIE XYZ Color Matching Functions"
+// by Wyman et al., which is close enough for rendering and needs no tables.
+func matching(wavelength float64) (float64, float64, float64) {
+	g := func(mu, sigma1, sigma2 float64) float64 {
+		sigma := sigma1
+		if wavelength >= mu {
+			sigma = sigma2
+		}
+		t := (wavelength - mu) / sigma
+		return math.Exp(-t * t / 2)
+	}
+	x := 1.056*g(599.8, 37.9, 31.0) + 0.362*g(442.0, 16.0, 26.7) - 0.065*g(501.1, 20.4, 26.2)
+	y := 0.821*g(568.8, 46.9, 40.5) + 0.286*g(530.9, 16.3, 31.1)
+	z := 1.217*g(437.0, 11.8, 36.0) + 0.681*g(459.0, 26.0, 13.8)
+	return x, y, z
+}
+
+// The matching functions at every nanometre of the sampled wavelengths, evaluating the fit is slow
+var matchingTable = tabulate()
+
+func tabulate() [][3]float64 {
+	table := make([][3]float64, int(MaxWavelength-MinWavelength)+1)
+	for i := range table {
+		x, y, z := matching(MinWavelength + float64(i))
+		table[i] = [3]float64{x, y, z}
+	}
+	return table
+}
+
+// tabulated interpolates the matching functions at a wavelength from the table
+func tabulated(wavelength float64) (float64, float64, float64) {
+	position := math.Max(0, math.Min(wavelength-MinWavelength, MaxWavelength-MinWavelength))
+	i := int(position)
+	if i == len(matchingTable)-1 {
+		i--
+	}
+	t := position - float64(i)
+	a, b := matchingTable[i], matchingTable[i+1]
+	return a[0] + t*(b[0]-a[0]), a[1] + t*(b[1]-a[1]), a[2] + t*(b[2]-a[2])
+}
+
+// xyzToRGB converts CIE XYZ to linear sRGB with the D65 white point
+func xyzToRGB(x, y, z float64) (float64, float64, float64) {
+	return 3.2404542*x - 1.5371385*y - 0.4985314*z,
+		-0.9692660*x + 1.8760108*y + 0.0415560*z,
+		0.0556434*x - 0.2040259*y + 1.0572252*z
+}
+
+// The integral of the Y matching function over the sampled wavelengths, and the RGB color of a flat spectrum
+// normalised by it, which ToRGB divides out so a flat spectrum is white instead of the slightly pink illuminant E
+var integralY, white = integrate()
+
+func integrate() (float64, [3]float64) {
+	const step = 0.1
+	var x, y, z float64
+	for wavelength := MinWavelength; wavelength <= MaxWavelength; wavelength += step {
+		dx, dy, dz := matching(wavelength)
+		x += dx * step
+		y += dy * step
+		z += dz * step
+	}
+	r, g, b := xyzToRGB(x/y, 1, z/y)
+	return y, [3]float64{r, g, b}
+}
+
+// Integrate returns the linear sRGB color of a spectrum, evaluating it at n evenly spaced wavelengths
+// A spectrum which varies slowly, like the reflectance of a material, needs only a few dozen.
+func Integrate(spectrum func(wavelength float64) float64, n int) color.RGB {
+	var r, g, b float32
+	for i := 0; i < n; i++ {
+		wavelength := SampleWavelength((float64(i) + 0.5) / float64(n))
+		c := ToRGB(wavelength, spectrum(wavelength))
+		r += c.R
+		g += c.G
+		b += c.B
+	}
+	return color.New(r/float32(n), g/float32(n), b/float32(n))
+}
@@ -0,0 +1,75 @@
+package spectrum
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"math"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for _, c := range []color.RGB{
+		color.New(1, 1, 1),
+		color.New(0.5, 0.5, 0.5),
+		color.New(0.8, 0.3, 0.3),
+		color.New(0.1, 0.2, 0.5),
+		color.New(0.5, 0.7, 1),
+		color.New(0.8, 0.6, 0.2),
+	} {
+		got := Integrate(func(wavelength float64) float64 {
+			return Reflectance(c, wavelength)
+		}, 3400)
+		if math.Abs(float64(got.R-c.R)) > 0.05 || math.Abs(float64(got.G-c.G)) > 0.05 || math.Abs(float64(got.B-c.B)) > 0.05 {
+			t.Errorf("%v comes back from its spectrum as %v", c, got)
+		}
+	}
+}
+
+func TestIlluminantRoundTrip(t *testing.T) {
+	for _, c := range []color.RGB{
+		color.New(1, 1, 1),
+		color.New(10, 10, 10),
+		color.New(4, 1, 0.2),
+		color.New(0.5, 3, 0.1),
+		color.New(0, 0, 2),
+		color.New(2, 0.5, 0.5),
+	} {
+		got := Integrate(func(wavelength float64) float64 {
+			return Illuminant(c, wavelength)
+		}, 3400)
+		for _, channel := range [][2]float32{{got.R, c.R}, {got.G, c.G}, {got.B, c.B}} {
+			if math.Abs(float64(channel[0]-channel[1])) > 0.01*float64(c.R+c.G+c.B) {
+				t.Errorf("light of %v comes back from its spectrum as %v", c, got)
+				break
+			}
+		}
+	}
+}
+
+func TestGlasses(t *testing.T) {
+	// Refraction indices at the helium d line from the datasheets
+	for name, want := range map[string]float64{"bk7": 1.5168, "fusedSilica": 1.4585, "diamond": 2.4175} {
+		ior, err := GlassByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ior.At(HeliumD); math.Abs(got-want) > 1e-3 {
+			t.Errorf("%s has refraction index %.4f, want %.4f", name, got, want)
+		}
+		if ior.At(450) <= ior.At(650) {
+			t.Errorf("%s refracts red light more than blue light", name)
+		}
+	}
+}
+
+func TestBlackbody(t *testing.T) {
+	// Close to the D65 white point, within the error of the fitted matching functions
+	if c := Blackbody(6504); math.Abs(float64(c.R-c.G)) > 0.1 || math.Abs(float64(c.B-c.G)) > 0.1 {
+		t.Errorf("6504 K is %v, not white", c)
+	}
+	if c := Blackbody(2700); c.R <= c.G || c.G <= c.B {
+		t.Errorf("2700 K is %v, not orange", c)
+	}
+	if c := Blackbody(12000); c.B <= c.R {
+		t.Errorf("12000 K is %v, not blue", c)
+	}
+}
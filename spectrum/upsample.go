@@ -0,0 +1,140 @@
+package spectrum
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"math"
+)
+
+// Reflectance spectra of the pure colors, in 10 bins spanning the visible wavelengths
+// From "An RGB to Spectrum Conversion for Reflectances" by Smits.
+var (
+	smitsWhite   = [10]float64{1, 1, 0.9999, 0.9993, 0.9992, 0.9998, 1, 1, 1, 1}
+	smitsCyan    = [10]float64{0.9710, 0.9426, 1.0007, 1.0007, 1.0007, 1.0007, 0.1564, 0, 0, 0}
+	smitsMagenta = [10]float64{1, 1, 0.9685, 0.2229, 0, 0.0458, 0.8369, 1, 1, 0.9959}
+	smitsYellow  = [10]float64{0.0001, 0, 0.1088, 0.6651, 1, 1, 0.9996, 0.9586, 0.9685, 0.9840}
+	smitsRed     = [10]float64{0.1012, 0.0515, 0, 0, 0, 0, 0.8325, 1.0149, 1.0149, 1.0149}
+	smitsGreen   = [10]float64{0, 0, 0.0273, 0.7937, 1, 0.9418, 0.1719, 0, 0, 0.0025}
+	smitsBlue    = [10]float64{1, 1, 0.8916, 0.3323, 0, 0, 0.0003, 0.0369, 0.0483, 0.0496}
+)
+
+// Reflectance returns the value at a wavelength of a smooth spectrum with the color c
+// The spectrum is built from the spectra of white and of the pure colors, like Smits describes, so gray colors give
+// flat spectra. It is meant for reflectances between 0 and 1, which it gives back approximately, lights use Illuminant.
+func Reflectance(c color.RGB, wavelength float64) float64 {
+	r, g, b := float64(c.R), float64(c.G), float64(c.B)
+	at := func(spectrum *[10]float64) float64 {
+		return sampleBins(spectrum, wavelength)
+	}
+
+	switch {
+	case r <= g && r <= b:
+		if g <= b {
+			return r*at(&smitsWhite) + (g-r)*at(&smitsCyan) + (b-g)*at(&smitsBlue)
+		}
+		return r*at(&smitsWhite) + (b-r)*at(&smitsCyan) + (g-b)*at(&smitsGreen)
+	case g <= r && g <= b:
+		if r <= b {
+			return g*at(&smitsWhite) + (r-g)*at(&smitsMagenta) + (b-r)*at(&smitsBlue)
+		}
+		return g*at(&smitsWhite) + (b-g)*at(&smitsMagenta) + (r-b)*at(&smitsRed)
+	default:
+		if r <= g {
+			return b*at(&smitsWhite) + (r-b)*at(&smitsYellow) + (g-r)*at(&smitsGreen)
+		}
+		return b*at(&smitsWhite) + (g-b)*at(&smitsYellow) + (r-g)*at(&smitsRed)
+	}
+}
+
+// sampleBins interpolates linearly between the centers of the bins
+func sampleBins(bins *[10]float64, wavelength float64) float64 {
+	const width = (MaxWavelength - MinWavelength) / 10
+	position := (wavelength-MinWavelength)/width - 0.5
+	if position <= 0 {
+		return bins[0]
+	}
+	if position >= 9 {
+		return bins[9]
+	}
+	i := int(position)
+	t := position - math.Floor(position)
+	return bins[i]*(1-t) + bins[i+1]*t
+}
+
+// Spectra an illuminant with channels in each order is made of, the same as Reflectance uses: white, the secondary
+// color of the two largest channels and the primary color of the largest one
+var illuminantBases = [6][3]*[10]float64{
+	{&smitsWhite, &smitsCyan, &smitsBlue},    // r <= g <= b
+	{&smitsWhite, &smitsCyan, &smitsGreen},   // r <= b < g
+	{&smitsWhite, &smitsMagenta, &smitsBlue}, // g < r <= b
+	{&smitsWhite, &smitsMagenta, &smitsRed},  // g <= b < r
+	{&smitsWhite, &smitsYellow, &smitsGreen}, // b < r <= g
+	{&smitsWhite, &smitsYellow, &smitsRed},   // b < g < r
+}
+
+// Matrices turning a color into the weights of the bases of its order, so the spectrum comes back as the color
+var illuminantWeights = illuminantMatrices()
+
+func illuminantMatrices() [6][3][3]float64 {
+	var matrices [6][3][3]float64
+	for i, bases := range illuminantBases {
+		// The colors of the bases are the columns of the matrix to invert
+		var m [3][3]float64
+		for j, basis := range bases {
+			basis := basis
+			c := Integrate(func(wavelength float64) float64 {
+				return sampleBins(basis, wavelength)
+			}, 3400)
+			m[0][j], m[1][j], m[2][j] = float64(c.R), float64(c.G), float64(c.B)
+		}
+		matrices[i] = invert(m)
+	}
+	return matrices
+}
+
+// Illuminant returns the value at a wavelength of a smooth spectrum of light with the color c
+// It is made of the same spectra as Reflectance, weighted so that the spectrum integrates back to c exactly, which
+// keeps bright and saturated lights the same color in RGB and spectral renders. Channels must not be negative.
+func Illuminant(c color.RGB, wavelength float64) float64 {
+	r, g, b := float64(c.R), float64(c.G), float64(c.B)
+	var order int
+	switch {
+	case r <= g && r <= b:
+		order = 0
+		if g > b {
+			order = 1
+		}
+	case g <= r && g <= b:
+		order = 2
+		if r > b {
+			order = 3
+		}
+	default:
+		order = 4
+		if g < r {
+			order = 5
+		}
+	}
+
+	m, bases := &illuminantWeights[order], &illuminantBases[order]
+	var value float64
+	for i := range bases {
+		value += (m[i][0]*r + m[i][1]*g + m[i][2]*b) * sampleBins(bases[i], wavelength)
+	}
+	return value
+}
+
+// invert returns the inverse of a 3 by 3 matrix
+func invert(m [3][3]float64) [3][3]float64 {
+	cofactor := func(i, j int) float64 {
+		a, b := m[(i+1)%3], m[(i+2)%3]
+		return a[(j+1)%3]*b[(j+2)%3] - a[(j+2)%3]*b[(j+1)%3]
+	}
+	determinant := m[0][0]*cofactor(0, 0) + m[0][1]*cofactor(0, 1) + m[0][2]*cofactor(0, 2)
+	var inverse [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			inverse[j][i] = cofactor(i, j) / determinant
+		}
+	}
+	return inverse
+}
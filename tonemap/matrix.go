@@ -0,0 +1,35 @@
+package tonemap
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"math"
+)
+
+// A 3x3 color space matrix, in row major order
+type matrix [3][3]float64
+
+// A color with float64 precision for intermediate calculations
+type vec3 [3]float64
+
+// mul multiplies the color c by the matrix
+func (m matrix) mul(c color.RGB) vec3 {
+	r, g, b := float64(c.R), float64(c.G), float64(c.B)
+	return vec3{
+		m[0][0]*r + m[0][1]*g + m[0][2]*b,
+		m[1][0]*r + m[1][1]*g + m[1][2]*b,
+		m[2][0]*r + m[2][1]*g + m[2][2]*b,
+	}
+}
+
+func (v vec3) rgb() color.RGB {
+	return color.New(float32(v[0]), float32(v[1]), float32(v[2]))
+}
+
+// clamped returns the color clamped between 0 and 1
+func (v vec3) clamped() color.RGB {
+	return color.New(
+		float32(math.Min(math.Max(v[0], 0), 1)),
+		float32(math.Min(math.Max(v[1], 0), 1)),
+		float32(math.Min(math.Max(v[2], 0), 1)),
+	)
+}
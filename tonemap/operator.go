@@ -0,0 +1,175 @@
+package tonemap
+
+import (
+	"fmt"
+	"github.com/thijsheijden/go-raytracer/color"
+	"math"
+)
+
+// Operator maps linear scene radiance onto linear display values between 0 and 1
+type Operator interface {
+	Map(c color.RGB) color.RGB
+}
+
+// Operators lists the names accepted by OperatorByName
+var Operators = []string{"clamp", "reinhard", "reinhard-extended", "aces", "agx"}
+
+// OperatorByName returns the operator with the given name
+// whitePoint is the luminance mapped to white by the extended Reinhard operator
+func OperatorByName(name string, whitePoint float64) (Operator, error) {
+	switch name {
+	case "clamp":
+		return Clamp(), nil
+	case "reinhard":
+		return Reinhard(), nil
+	case "reinhard-extended":
+		if whitePoint <= 0 {
+			return nil, fmt.Errorf("white point must be positive, got %v", whitePoint)
+		}
+		return ExtendedReinhard(whitePoint), nil
+	case "aces":
+		return ACES(), nil
+	case "agx":
+		return AgX(), nil
+	}
+	return nil, fmt.Errorf("unknown tone mapping operator %q", name)
+}
+
+// Clamp operator, cuts off everything above 1
+type clampOperator struct{}
+
+// Clamp returns an operator which only clamps, blowing out highlights like the display would
+func Clamp() Operator {
+	return clampOperator{}
+}
+
+func (o clampOperator) Map(c color.RGB) color.RGB {
+	return color.New(clamp(c.R), clamp(c.G), clamp(c.B))
+}
+
+// Reinhard operator, compresses luminance by L / (1 + L)
+type reinhard struct{}
+
+// Reinhard returns the simple Reinhard operator
+func Reinhard() Operator {
+	return reinhard{}
+}
+
+func (o reinhard) Map(c color.RGB) color.RGB {
+	return scaleLuminance(c, func(l float64) float64 {
+		return l / (1 + l)
+	})
+}
+
+// Extended Reinhard operator, which maps the white point luminance to 1 instead of infinity
+type extendedReinhard struct {
+	whitePoint float64
+}
+
+// ExtendedReinhard returns the extended Reinhard operator with the given white point
+func ExtendedReinhard(whitePoint float64) Operator {
+	return extendedReinhard{
+		whitePoint: whitePoint,
+	}
+}
+
+func (o extendedReinhard) Map(c color.RGB) color.RGB {
+	w2 := o.whitePoint * o.whitePoint
+	return scaleLuminance(c, func(l float64) float64 {
+		return l * (1 + l/w2) / (1 + l)
+	})
+}
+
+// ACES filmic operator
+type aces struct{}
+
+// ACES returns Stephen Hill's fit of the ACES reference rendering and output transforms
+func ACES() Operator {
+	return aces{}
+}
+
+var acesInput = matrix{
+	{0.59719, 0.35458, 0.04823},
+	{0.07600, 0.90834, 0.01566},
+	{0.02840, 0.13383, 0.83777},
+}
+
+var acesOutput = matrix{
+	{1.60475, -0.53108, -0.07367},
+	{-0.10208, 1.10813, -0.00605},
+	{-0.00327, -0.07276, 1.07602},
+}
+
+func (o aces) Map(c color.RGB) color.RGB {
+	v := acesInput.mul(c)
+	for i := range v {
+		a := v[i]*(v[i]+0.0245786) - 0.000090537
+		b := v[i]*(0.983729*v[i]+0.4329510) + 0.238081
+		v[i] = a / b
+	}
+	return acesOutput.mul(v.rgb()).clamped()
+}
+
+// AgX operator
+type agx struct{}
+
+// AgX returns the AgX operator, using a polynomial fit of the default contrast curve
+func AgX() Operator {
+	return agx{}
+}
+
+var agxInset = matrix{
+	{0.842479062253094, 0.0784335999999992, 0.0792237451477643},
+	{0.0423282422610123, 0.878468636469772, 0.0791661274605434},
+	{0.0423756549057051, 0.0784336, 0.879142973793104},
+}
+
+var agxOutset = matrix{
+	{1.19687900512017, -0.0980208811401368, -0.0990297440797205},
+	{-0.0528968517574562, 1.15190312990417, -0.0989611768448433},
+	{-0.0529716355144438, -0.0980434501171241, 1.15107367264116},
+}
+
+// Exposure range of the AgX log encoding, in stops around middle grey
+const agxMinEV, agxMaxEV = -12.47393, 4.026069
+
+func (o agx) Map(c color.RGB) color.RGB {
+	v := agxInset.mul(c)
+	for i := range v {
+		// Log2 encoding, normalised to the exposure range
+		x := math.Log2(math.Max(v[i], 1e-10))
+		x = (math.Min(math.Max(x, agxMinEV), agxMaxEV) - agxMinEV) / (agxMaxEV - agxMinEV)
+
+		// Sigmoid contrast curve
+		x2 := x * x
+		x4 := x2 * x2
+		v[i] = 15.5*x4*x2 - 40.14*x4*x + 31.96*x4 - 6.868*x2*x + 0.4298*x2 + 0.1191*x - 0.00232
+	}
+
+	// The curve outputs display encoded values, go back to linear
+	v = agxOutset.mul(v.rgb())
+	for i := range v {
+		v[i] = math.Pow(math.Max(v[i], 0), 2.2)
+	}
+	return v.clamped()
+}
+
+// scaleLuminance scales a color so its luminance becomes f(luminance), preserving its hue
+func scaleLuminance(c color.RGB, f func(float64) float64) color.RGB {
+	l := c.Luminance()
+	if l <= 0 {
+		return color.New(0, 0, 0)
+	}
+	s := float32(f(l) / l)
+	return Clamp().Map(c.Scale(s))
+}
+
+func clamp(v float32) float32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
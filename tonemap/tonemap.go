@@ -0,0 +1,70 @@
+package tonemap
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"github.com/thijsheijden/go-raytracer/film"
+	"image"
+	"math"
+)
+
+// Settings describe how linear radiance is turned into a displayable image
+type Settings struct {
+	Exposure float64  // Exposure compensation in stops (EV)
+	Operator Operator // The tone mapping operator
+}
+
+// Default returns settings which leave exposure untouched and clamp highlights
+func Default() Settings {
+	return Settings{
+		Exposure: 0,
+		Operator: Clamp(),
+	}
+}
+
+// Develop maps a linear radiance value to an sRGB encoded display color
+func (s Settings) Develop(c color.RGB) color.RGB {
+	c = s.Operator.Map(c.Scale(float32(math.Exp2(s.Exposure))))
+	return color.New(srgb(c.R), srgb(c.G), srgb(c.B))
+}
+
+// Image develops the full film into an sRGB image
+func (s Settings) Image(f *film.Film) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, f.Width, f.Height))
+	for y := 0; y < f.Height; y++ {
+		for x := 0; x < f.Width; x++ {
+			img.SetRGBA(x, y, s.Develop(f.Pixel(x, y)).RGBA())
+		}
+	}
+	return img
+}
+
+// srgb applies the sRGB transfer function to a linear value between 0 and 1
+func srgb(v float32) float32 {
+	if v <= 0.0031308 {
+		return 12.92 * v
+	}
+	return float32(1.055*math.Pow(float64(v), 1/2.4) - 0.055)
+}
+
+// Thumbnail develops the film into a smaller sRGB image of width by height pixels
+// Every thumbnail pixel develops the average linear color of the film pixels it covers.
+func (s Settings) Thumbnail(f *film.Film, width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for ty := 0; ty < height; ty++ {
+		y0, y1 := ty*f.Height/height, (ty+1)*f.Height/height
+		for tx := 0; tx < width; tx++ {
+			x0, x1 := tx*f.Width/width, (tx+1)*f.Width/width
+
+			sum := color.New(0, 0, 0)
+			n := 0
+			for y := y0; y < y1 || y == y0; y++ {
+				for x := x0; x < x1 || x == x0; x++ {
+					sum = sum.Add(f.Pixel(x, y))
+					n++
+				}
+			}
+			img.SetRGBA(tx, ty, s.Develop(sum.Scale(1/float32(n))).RGBA())
+		}
+	}
+	return img
+}
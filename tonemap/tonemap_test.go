@@ -0,0 +1,89 @@
+package tonemap
+
+import (
+	"github.com/thijsheijden/go-raytracer/color"
+	"math"
+	"testing"
+)
+
+func gray(v float32) color.RGB {
+	return color.New(v, v, v)
+}
+
+func closeColors(a, b color.RGB, tolerance float64) bool {
+	return math.Abs(float64(a.R-b.R)) <= tolerance && math.Abs(float64(a.G-b.G)) <= tolerance && math.Abs(float64(a.B-b.B)) <= tolerance
+}
+
+func TestOperators(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		operator Operator
+		in, want color.RGB
+	}{
+		{"clamp", Clamp(), color.New(2, 0.5, -1), color.New(1, 0.5, 0)},
+		{"reinhard", Reinhard(), gray(1), gray(0.5)},
+		{"reinhard black", Reinhard(), gray(0), gray(0)},
+		{"reinhard-extended at the white point", ExtendedReinhard(4), gray(4), gray(1)},
+		{"reinhard-extended below the white point", ExtendedReinhard(4), gray(1), gray(0.53125)},
+		{"reinhard-extended above the white point", ExtendedReinhard(4), gray(8), gray(1)},
+		{"aces black", ACES(), gray(0), gray(0)},
+		{"aces bright", ACES(), gray(1000), gray(1)},
+	} {
+		if got := test.operator.Map(test.in); !closeColors(got, test.want, 1e-3) {
+			t.Errorf("%s maps %v to %v, want %v", test.name, test.in, got, test.want)
+		}
+	}
+}
+
+// TestMonotonic checks that every operator keeps brighter grays brighter and stays between 0 and 1
+func TestMonotonic(t *testing.T) {
+	for _, name := range Operators {
+		operator, err := OperatorByName(name, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		previous := float32(-1)
+		for v := float32(0); v <= 64; v += 0.01 {
+			got := operator.Map(gray(v))
+			if got.G < previous || got.G < 0 || got.G > 1 {
+				t.Errorf("%s maps gray %v to %v, after %v for a darker gray", name, v, got.G, previous)
+				break
+			}
+			previous = got.G
+		}
+	}
+}
+
+func TestExposure(t *testing.T) {
+	for _, test := range []struct {
+		exposure float64
+		in, want float32 // Linear, the developed color is compared after the sRGB transfer function
+	}{
+		{0, 0.5, 0.5},
+		{1, 0.25, 0.5},
+		{-2, 1, 0.25},
+		{1, 0.75, 1},
+	} {
+		settings := Settings{Exposure: test.exposure, Operator: Clamp()}
+		if got, want := settings.Develop(gray(test.in)), gray(srgb(test.want)); !closeColors(got, want, 1e-6) {
+			t.Errorf("%v at %+v EV develops to %v, want %v", test.in, test.exposure, got, want)
+		}
+	}
+}
+
+func TestSRGB(t *testing.T) {
+	for _, test := range []struct {
+		linear, want float32
+	}{
+		{0, 0},
+		{1, 1},
+		{0.0031308, 0.04045},
+		{0.001, 0.01292},
+		{0.5, 0.735357},
+		{0.18, 0.461356},
+	} {
+		if got := srgb(test.linear); math.Abs(float64(got-test.want)) > 1e-4 {
+			t.Errorf("sRGB encodes %v as %v, want %v", test.linear, got, test.want)
+		}
+	}
+}
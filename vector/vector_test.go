@@ -0,0 +1,58 @@
+package vector
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Results are stored here so the compiler can not optimise the benchmarked calls away
+var (
+	sinkVector Vector
+	sinkFloat  float64
+)
+
+var a, b = New(0.3, -1.2, 2.5), New(-0.7, 0.4, 0.9).Normalise()
+
+func BenchmarkAdd(bench *testing.B) {
+	for i := 0; i < bench.N; i++ {
+		sinkVector = a.Add(b)
+	}
+}
+
+func BenchmarkDot(bench *testing.B) {
+	for i := 0; i < bench.N; i++ {
+		sinkFloat = a.Dot(b)
+	}
+}
+
+func BenchmarkCross(bench *testing.B) {
+	for i := 0; i < bench.N; i++ {
+		sinkVector = a.Cross(b)
+	}
+}
+
+func BenchmarkNormalise(bench *testing.B) {
+	for i := 0; i < bench.N; i++ {
+		sinkVector = a.Normalise()
+	}
+}
+
+func BenchmarkReflect(bench *testing.B) {
+	for i := 0; i < bench.N; i++ {
+		sinkVector = a.Reflect(b)
+	}
+}
+
+func BenchmarkRefract(bench *testing.B) {
+	direction := a.Normalise()
+	for i := 0; i < bench.N; i++ {
+		sinkVector = direction.Refract(b, 1/1.5)
+	}
+}
+
+func BenchmarkRandomInUnitSphere(bench *testing.B) {
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < bench.N; i++ {
+		sinkVector = RandomInUnitSphere(random)
+	}
+}